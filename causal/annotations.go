@@ -0,0 +1,102 @@
+package causal
+
+import (
+	"strings"
+)
+
+// Annotation is a free-form note a user attached to either a variable or
+// an edge (From -> To) of the map.  Names are normalized.
+type Annotation struct {
+	Variable string `json:"variable,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Note     string `json:"note"`
+}
+
+func (a Annotation) isEdge() bool {
+	return a.Variable == ""
+}
+
+// AnnotateVariable attaches a note to the variable.
+func (m *Map) AnnotateVariable(name, note string) {
+	m.Annotations = append(m.Annotations, Annotation{
+		Variable: normalizeVariable(name),
+		Note:     note,
+	})
+}
+
+// AnnotateEdge attaches a note to the edge from -> to.
+func (m *Map) AnnotateEdge(from, to, note string) {
+	m.Annotations = append(m.Annotations, Annotation{
+		From: normalizeVariable(from),
+		To:   normalizeVariable(to),
+		Note: note,
+	})
+}
+
+// VariableNotes returns the notes attached to the variable, in the order
+// they were added.
+func (m *Map) VariableNotes(name string) []string {
+	name = normalizeVariable(name)
+	var notes []string
+	for _, a := range m.Annotations {
+		if !a.isEdge() && a.Variable == name {
+			notes = append(notes, a.Note)
+		}
+	}
+	return notes
+}
+
+// EdgeNotes returns the notes attached to the edge from -> to, in the
+// order they were added.
+func (m *Map) EdgeNotes(from, to string) []string {
+	from, to = normalizeVariable(from), normalizeVariable(to)
+	var notes []string
+	for _, a := range m.Annotations {
+		if a.isEdge() && a.From == from && a.To == to {
+			notes = append(notes, a.Note)
+		}
+	}
+	return notes
+}
+
+// carryAnnotations returns the annotations from maps that still apply to
+// dst after resolving each name through resolve: those whose variable or
+// edge dst has.  Duplicates are dropped.
+func carryAnnotations(dst *Map, maps []*Map, resolve func(string) string) []Annotation {
+	vars := dst.Variables()
+	edges := make(Set[string])
+	for from, tos := range dst.OutgoingEdges() {
+		for _, to := range tos {
+			edges.Add(from + "\x00" + to)
+		}
+	}
+
+	var carried []Annotation
+	seen := make(map[Annotation]bool)
+	for _, m := range maps {
+		for _, a := range m.Annotations {
+			if a.isEdge() {
+				a.From, a.To = resolve(a.From), resolve(a.To)
+				if !edges.Contains(a.From + "\x00" + a.To) {
+					continue
+				}
+			} else {
+				a.Variable = resolve(a.Variable)
+				if !vars.Contains(a.Variable) {
+					continue
+				}
+			}
+			if !seen[a] {
+				seen[a] = true
+				carried = append(carried, a)
+			}
+		}
+	}
+	return carried
+}
+
+// tooltip joins notes for use as a DOT tooltip.
+func tooltip(notes []string) string {
+	return strings.Join(notes, "; ")
+}
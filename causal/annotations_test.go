@@ -0,0 +1,40 @@
+package causal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	m.AnnotateVariable("Tax Burden", "needs source")
+	m.AnnotateEdge("Tensions", "clashes", "check the 1770 timeline")
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	parsed, err := ParseMap(data)
+	require.NoError(t, err)
+	assert.Equal(t, m.Annotations, parsed.Annotations)
+	assert.Equal(t, []string{"needs source"}, parsed.VariableNotes("tax burden"))
+	assert.Equal(t, []string{"check the 1770 timeline"}, parsed.EdgeNotes("tensions", "Clashes"))
+
+	dot := parsed.DOT()
+	assert.Contains(t, dot, `"tax burden" [label="Tax Burden", tooltip="needs source"]`)
+	assert.Contains(t, dot, `"tensions" -> "clashes" [label="+", tooltip="check the 1770 timeline"]`)
+
+	canonical := parsed.Canonicalize(CanonicalizeOptions{DropEmptyChains: true})
+	assert.Equal(t, m.Annotations, canonical.Annotations)
+
+	merged := Merge([]*Map{parsed, testMap1}, MergeOptions{Aliases: map[string]string{"Taxes": "Tax Burden"}})
+	assert.Equal(t, m.Annotations, merged.Annotations)
+
+	renamed, _ := parsed.RenameVariable("Clashes", "Violence")
+	assert.Equal(t, []string{"check the 1770 timeline"}, renamed.EdgeNotes("tensions", "violence"))
+}
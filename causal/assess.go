@@ -0,0 +1,115 @@
+package causal
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CausalityAssessment estimates whether some background text describes
+// causal structure a map could be extracted from.
+type CausalityAssessment struct {
+	// Confidence is between 0 (no sign of causal content) and 1.
+	Confidence float64
+	// Cues are the causal words found in the text, in their base forms
+	// ("cause" for "caused"), in order of first appearance.
+	Cues []string
+}
+
+// minCausalConfidence is the confidence below which text probably
+// doesn't describe causal relationships.
+const minCausalConfidence = 0.3
+
+func (a CausalityAssessment) IsCausal() bool {
+	return a.Confidence >= minCausalConfidence
+}
+
+// causalCues are the words that signal cause and effect, or a change in
+// some quantity, each listed with its inflections.  Only whole words
+// match, so "leader", "fellow", "effective" and "driver" aren't cues.
+var causalCues = map[string][]string{
+	"affect":      {"affect", "affects", "affected", "affecting"},
+	"because":     {"because"},
+	"boost":       {"boost", "boosts", "boosted", "boosting"},
+	"cause":       {"cause", "causes", "caused", "causing"},
+	"consequence": {"consequence", "consequences", "consequently"},
+	"decline":     {"decline", "declines", "declined", "declining"},
+	"decrease":    {"decrease", "decreases", "decreased", "decreasing"},
+	"drive":       {"drive", "drives", "drove", "driven", "driving"},
+	"due":         {"due"},
+	"effect":      {"effect", "effects"},
+	"fall":        {"fall", "falls", "fell", "fallen", "falling"},
+	"fuel":        {"fuel", "fuels", "fueled", "fuelled", "fueling", "fuelling"},
+	"grow":        {"grow", "grows", "grew", "grown", "growing", "growth"},
+	"impact":      {"impact", "impacts", "impacted", "impacting"},
+	"increase":    {"increase", "increases", "increased", "increasing"},
+	"influence":   {"influence", "influences", "influenced", "influencing"},
+	"lead":        {"lead", "leads", "led"},
+	"lower":       {"lower", "lowers", "lowered", "lowering"},
+	"raise":       {"raise", "raises", "raised", "raising"},
+	"reduce":      {"reduce", "reduces", "reduced", "reducing", "reduction"},
+	"result":      {"result", "results", "resulted", "resulting"},
+	"rise":        {"rise", "rises", "rose", "risen", "rising"},
+	"therefore":   {"therefore"},
+	"trigger":     {"trigger", "triggers", "triggered", "triggering"},
+}
+
+// causalCueForms maps each inflection in causalCues to its cue.
+var causalCueForms = func() map[string]string {
+	forms := make(map[string]string)
+	for cue, inflections := range causalCues {
+		for _, form := range inflections {
+			forms[form] = cue
+		}
+	}
+	return forms
+}()
+
+func causalCue(word string) (string, bool) {
+	cue, ok := causalCueForms[word]
+	return cue, ok
+}
+
+// AssessBackground estimates whether the background knowledge describes
+// causal relationships at all, so callers can warn before a model invents
+// some.  It is a heuristic that doesn't call a model: the confidence is
+// the share of sentences containing at least one causal cue, doubled (and
+// capped at 1) since even causal text has plenty of purely descriptive
+// sentences.
+func AssessBackground(background string) CausalityAssessment {
+	var a CausalityAssessment
+
+	sentences := strings.FieldsFunc(background, func(r rune) bool {
+		return r == '.' || r == '!' || r == '?' || r == '\n'
+	})
+
+	seen := make(Set[string])
+	var n, causal int
+	for _, sentence := range sentences {
+		words := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		n++
+
+		found := false
+		for _, word := range words {
+			if cue, ok := causalCue(word); ok {
+				found = true
+				if !seen.Contains(cue) {
+					seen.Add(cue)
+					a.Cues = append(a.Cues, cue)
+				}
+			}
+		}
+		if found {
+			causal++
+		}
+	}
+
+	if n > 0 {
+		a.Confidence = min(1, 2*float64(causal)/float64(n))
+	}
+	return a
+}
@@ -0,0 +1,25 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssessBackground(t *testing.T) {
+	recipe := "Preheat the oven to 350 degrees. Combine flour, sugar and eggs in a bowl. Bake for 30 minutes, then serve warm."
+	a := AssessBackground(recipe)
+	assert.Less(t, a.Confidence, 0.3)
+	assert.False(t, a.IsCausal())
+
+	history := "The Stamp Act increased the tax burden on the colonies. Higher taxes led to protests. Because of the protests, tensions with British troops rose, which caused violent clashes."
+	a = AssessBackground(history)
+	assert.True(t, a.IsCausal())
+	assert.Equal(t, []string{"increase", "lead", "because", "rise", "cause"}, a.Cues)
+
+	// words that merely start like a cue aren't one
+	leadership := "The leader and their fellow officers were effective. The driver waited by the carriage."
+	a = AssessBackground(leadership)
+	assert.Empty(t, a.Cues)
+	assert.False(t, a.IsCausal())
+}
@@ -0,0 +1,143 @@
+// Package causaltest provides a fake causal.Diagrammer, for testing code
+// that uses a diagrammer without calling a model.
+package causaltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/isee-systems/sd-ai/causal"
+)
+
+// Diagrammer responds to every call with the same map, or error.  It
+// records the prompts and background it was called with.
+type Diagrammer struct {
+	Map *causal.Map
+	Err error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Call is a single call made to the fake diagrammer.
+type Call struct {
+	Prompt     string
+	Background string
+}
+
+var _ causal.Diagrammer = &Diagrammer{}
+
+func NewDiagrammer(m *causal.Map, err error) *Diagrammer {
+	return &Diagrammer{
+		Map: m,
+		Err: err,
+	}
+}
+
+// Calls returns the calls made so far, in order.
+func (d *Diagrammer) Calls() []Call {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Call(nil), d.calls...)
+}
+
+func (d *Diagrammer) record(prompt, background string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls = append(d.calls, Call{Prompt: prompt, Background: background})
+}
+
+func (d *Diagrammer) Generate(ctx context.Context, prompt, backgroundKnowledge string) (*causal.Map, error) {
+	d.record(prompt, backgroundKnowledge)
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return d.Map, nil
+}
+
+// GenerateRaw returns the map serialized as JSON as the raw content.
+func (d *Diagrammer) GenerateRaw(ctx context.Context, prompt, backgroundKnowledge string) (*causal.Map, string, error) {
+	m, err := d.Generate(ctx, prompt, backgroundKnowledge)
+	if err != nil {
+		return nil, "", err
+	}
+
+	content, err := json.Marshal(m)
+	if err != nil {
+		return nil, "", fmt.Errorf("json.Marshal: %w", err)
+	}
+	return m, string(content), nil
+}
+
+// GenerateWithReport reports on the map with causal.NewGenerationReport,
+// without constraints.
+func (d *Diagrammer) GenerateWithReport(ctx context.Context, prompt, backgroundKnowledge string) (*causal.Map, causal.GenerationReport, error) {
+	m, err := d.Generate(ctx, prompt, backgroundKnowledge)
+	if err != nil {
+		return nil, causal.GenerationReport{}, err
+	}
+	return m, causal.NewGenerationReport(m, causal.Constraints{}, backgroundKnowledge), nil
+}
+
+// GenerateCandidates only ever has the one map to offer.
+func (d *Diagrammer) GenerateCandidates(ctx context.Context, prompt, backgroundKnowledge string, n int) ([]*causal.Map, error) {
+	if n < 1 {
+		return nil, nil
+	}
+	m, err := d.Generate(ctx, prompt, backgroundKnowledge)
+	if err != nil {
+		return nil, err
+	}
+	return []*causal.Map{m}, nil
+}
+
+// GenerateChunked records a call per chunk, returning the map once.
+func (d *Diagrammer) GenerateChunked(ctx context.Context, prompt string, chunks []string) (*causal.Map, error) {
+	for _, chunk := range chunks {
+		if _, err := d.Generate(ctx, prompt, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return d.Map, nil
+}
+
+// GenerateFromURL doesn't fetch anything: it records url as the
+// background.
+func (d *Diagrammer) GenerateFromURL(ctx context.Context, prompt, url string) (*causal.Map, error) {
+	return d.Generate(ctx, prompt, url)
+}
+
+func (d *Diagrammer) Append(ctx context.Context, additionalBackground string) (*causal.Map, error) {
+	return d.Generate(ctx, "", additionalBackground)
+}
+
+// VerifyPolarities returns m unchanged, unless the diagrammer was given an
+// error.
+func (d *Diagrammer) VerifyPolarities(ctx context.Context, m *causal.Map) (*causal.Map, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return m, nil
+}
+
+// RefineEdge returns m unchanged, unless the diagrammer was given an
+// error.
+func (d *Diagrammer) RefineEdge(ctx context.Context, m *causal.Map, from, to string) (*causal.Map, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return m, nil
+}
+
+// Critique reports no suggestions, unless the diagrammer was given an
+// error.
+func (d *Diagrammer) Critique(ctx context.Context, m *causal.Map) (causal.CritiqueReport, error) {
+	if d.Err != nil {
+		return causal.CritiqueReport{}, d.Err
+	}
+	return causal.CritiqueReport{}, nil
+}
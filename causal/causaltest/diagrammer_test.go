@@ -0,0 +1,43 @@
+package causaltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/isee-systems/sd-ai/causal"
+)
+
+// loopCounter stands in for downstream code built on a diagrammer.
+type loopCounter struct {
+	d causal.Diagrammer
+}
+
+func (c loopCounter) describe(ctx context.Context, background string) (string, error) {
+	m, err := c.d.Generate(ctx, "find the feedback loops", background)
+	if err != nil {
+		return "", fmt.Errorf("Generate: %w", err)
+	}
+	return fmt.Sprintf("%d variables, %d loops", m.VariableCount(), len(m.Loops())), nil
+}
+
+func TestFakeDiagrammer(t *testing.T) {
+	m := causal.NewMap([]causal.Relationship{
+		{From: "Births", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Births", Polarity: "+"},
+	})
+	fake := NewDiagrammer(m, nil)
+
+	summary, err := loopCounter{fake}.describe(context.Background(), "rabbits")
+	require.NoError(t, err)
+	assert.Equal(t, "2 variables, 1 loops", summary)
+	assert.Equal(t, []Call{{Prompt: "find the feedback loops", Background: "rabbits"}}, fake.Calls())
+
+	failing := NewDiagrammer(nil, errors.New("model unavailable"))
+	_, err = loopCounter{failing}.describe(context.Background(), "rabbits")
+	assert.ErrorContains(t, err, "model unavailable")
+}
@@ -0,0 +1,51 @@
+package causal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Changelog describes, one change per line, how next differs from prev.
+// Lines start with "+" for added variables and edges ("+ added edge A→B
+// (+)"), "−" for removed ones ("− removed variable X"), and "~" for edges
+// whose polarity flipped ("~ flipped polarity of C→D (+ to -)").
+// Variable changes are listed first, then edge changes, each sorted.  An
+// empty changelog means the maps have the same structure.
+func Changelog(prev, next *Map) string {
+	names := next.displayNames()
+	for v, name := range prev.displayNames() {
+		if names[v] == "" {
+			names[v] = name
+		}
+	}
+
+	var b strings.Builder
+
+	prevVars, nextVars := prev.Variables(), next.Variables()
+	all := NewSet(prevVars.Slice()...)
+	for v := range nextVars {
+		all.Add(v)
+	}
+	for _, v := range all.Slice() {
+		switch {
+		case !prevVars.Contains(v):
+			fmt.Fprintf(&b, "+ added variable %s\n", names[v])
+		case !nextVars.Contains(v):
+			fmt.Fprintf(&b, "− removed variable %s\n", names[v])
+		}
+	}
+
+	for _, e := range diffEdges(prev, next) {
+		from, to := names[e.from], names[e.to]
+		switch {
+		case e.reference == "":
+			fmt.Fprintf(&b, "+ added edge %s→%s (%s)\n", from, to, e.result)
+		case e.result == "":
+			fmt.Fprintf(&b, "− removed edge %s→%s (%s)\n", from, to, e.reference)
+		case e.reference != e.result:
+			fmt.Fprintf(&b, "~ flipped polarity of %s→%s (%s to %s)\n", from, to, e.reference, e.result)
+		}
+	}
+
+	return b.String()
+}
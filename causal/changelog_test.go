@@ -0,0 +1,29 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangelog(t *testing.T) {
+	prev := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Weather", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "-"},
+	})
+	next := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Resistance", Polarity: "+"},
+	})
+
+	assert.Equal(t, `+ added variable Resistance
+− removed variable Weather
+~ flipped polarity of Clashes→Tensions (- to +)
++ added edge Tensions→Resistance (+)
+− removed edge Tensions→Weather (+)
+`, Changelog(prev, next))
+
+	assert.Empty(t, Changelog(next, NewMap(next.Relationships()).Minimal()))
+}
@@ -0,0 +1,78 @@
+package causal
+
+import (
+	"strings"
+)
+
+// DefaultCompoundSeparators are what SplitCompound splits on when given
+// no separators.
+var DefaultCompoundSeparators = []string{" and ", "/", ","}
+
+// splitCompound splits name on each of the separators (matched
+// case-insensitively), returning the trimmed, non-empty parts.
+func splitCompound(name string, separators []string) []string {
+	parts := []string{name}
+	for _, sep := range separators {
+		sep = strings.ToLower(sep)
+		var split []string
+		for _, part := range parts {
+			for {
+				i := strings.Index(strings.ToLower(part), sep)
+				if i < 0 {
+					break
+				}
+				split = append(split, part[:i])
+				part = part[i+len(sep):]
+			}
+			split = append(split, part)
+		}
+		parts = split
+	}
+
+	var trimmed []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			trimmed = append(trimmed, part)
+		}
+	}
+	if len(trimmed) == 0 {
+		return []string{name}
+	}
+	return trimmed
+}
+
+// SplitCompound returns a copy of m where compound variables like "Stress
+// and Anxiety" are split into one variable per part, each with all of the
+// compound's edges.  Separators are matched case-insensitively; word
+// separators need surrounding spaces so that e.g. "Bandwidth" isn't split.
+// If separators is nil, DefaultCompoundSeparators are used.  Not every
+// compound name is a mistake, so this is opt-in.
+func (m *Map) SplitCompound(separators []string) *Map {
+	if separators == nil {
+		separators = DefaultCompoundSeparators
+	}
+
+	var rels []Relationship
+	seen := make(map[edgeKey]bool)
+	for _, r := range m.Relationships() {
+		for _, from := range splitCompound(r.From, separators) {
+			for _, to := range splitCompound(r.To, separators) {
+				k := edgeKey{from: normalizeVariable(from), to: normalizeVariable(to), polarity: r.Polarity}
+				if k.from == k.to || seen[k] {
+					continue
+				}
+				seen[k] = true
+
+				split := r
+				split.From, split.To = from, to
+				rels = append(rels, split)
+			}
+		}
+	}
+
+	split := NewMap(rels)
+	split.Title = m.Title
+	split.Explanation = m.Explanation
+	split.Annotations = carryAnnotations(split, []*Map{m}, normalizeVariable)
+	return split
+}
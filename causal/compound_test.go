@@ -0,0 +1,25 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCompound(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Workload", To: "Stress and Anxiety", Polarity: "+"},
+		{From: "Stress and Anxiety", To: "Productivity", Polarity: "-"},
+		{From: "Productivity", To: "Workload", Polarity: "-"},
+		{From: "Bandwidth", To: "Productivity", Polarity: "+"},
+	})
+
+	split := m.SplitCompound(nil)
+	assert.Equal(t, NewSet("workload", "stress", "anxiety", "productivity", "bandwidth"), split.Variables())
+	assert.Equal(t, []string{"anxiety", "stress"}, split.Influences("Workload"))
+	assert.Equal(t, []string{"anxiety", "bandwidth", "stress"}, split.InfluencedBy("Productivity"))
+	assert.Len(t, split.Loops(), 2)
+
+	// unchanged without a matching separator
+	assert.Equal(t, m.Variables(), m.SplitCompound([]string{";"}).Variables())
+}
@@ -0,0 +1,18 @@
+package causal
+
+// LowConfidenceEdges returns the relationships the model gave a
+// confidence below threshold, for a person to review.  Relationships
+// without a confidence aren't included.
+func (m *Map) LowConfidenceEdges(threshold float64) []Relationship {
+	var low []Relationship
+	for _, r := range m.Relationships() {
+		if lowConfidence(r, threshold) {
+			low = append(low, r)
+		}
+	}
+	return low
+}
+
+func lowConfidence(r Relationship, threshold float64) bool {
+	return r.Confidence != nil && *r.Confidence < threshold
+}
@@ -0,0 +1,50 @@
+package causal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfidence(t *testing.T) {
+	var m Map
+	require.NoError(t, json.Unmarshal([]byte(`{"causal_chains": [{
+		"initial_variable": "Tax Burden",
+		"reasoning": "Taxes angered the colonists.",
+		"relationships": [
+			{"variable": "Tensions", "polarity": "+", "polarity_reasoning": "Taxes were resented.", "confidence": 0.9},
+			{"variable": "Clashes", "polarity": "+", "polarity_reasoning": "Tensions spilled over.", "confidence": 0.3},
+			{"variable": "Resistance", "polarity": "+", "polarity_reasoning": "Clashes inspired resistance.", "confidence": null}
+		]
+	}]}`), &m))
+
+	low := m.LowConfidenceEdges(0.5)
+	require.Len(t, low, 1)
+	assert.Equal(t, "Tensions", low[0].From)
+	assert.Equal(t, "Clashes", low[0].To)
+	assert.Equal(t, 0.3, *low[0].Confidence)
+	assert.Len(t, m.LowConfidenceEdges(0.95), 2)
+	assert.Empty(t, m.LowConfidenceEdges(0))
+
+	// confidence round-trips, and is omitted where the model gave none
+	out, err := json.Marshal(&m)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"confidence":0.9`)
+	assert.Contains(t, string(out), `"confidence":0.3`)
+	var roundTripped Map
+	require.NoError(t, json.Unmarshal(out, &roundTripped))
+	assert.Equal(t, m.Relationships(), roundTripped.Relationships())
+	assert.Nil(t, roundTripped.Relationships()[2].Confidence)
+
+	// and survives rebuilding the map from its relationships
+	rebuilt := NewMap(m.Relationships()).Minimal().LowConfidenceEdges(0.5)
+	require.Len(t, rebuilt, 1)
+	assert.Equal(t, low[0].Confidence, rebuilt[0].Confidence)
+
+	dot := m.DOT(WithLowConfidenceDashed(0.5))
+	assert.Contains(t, dot, `"tensions" -> "clashes" [label="+", style=dashed]`)
+	assert.Contains(t, dot, `"tax burden" -> "tensions" [label="+"]`)
+	assert.NotContains(t, m.DOT(), "dashed")
+}
@@ -0,0 +1,133 @@
+package causal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+// Constraints are the structural requirements a generated map must
+// satisfy.  Zero values mean "unconstrained".
+type Constraints struct {
+	MinVariables int
+	MaxVariables int
+	MinFeedback  int
+	MaxFeedback  int
+	Variables    []string
+}
+
+// MissingVariables returns the required variables that don't appear in m.
+func (c Constraints) MissingVariables(m *Map) []string {
+	vars := m.Variables()
+
+	var missing []string
+	for _, v := range c.Variables {
+		if !vars.Contains(normalizeVariable(v)) {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// Violations describes, in terms suitable for re-prompting a model, each
+// way in which m fails to satisfy the constraints.  Each message quantifies
+// the gap so the model knows exactly how far off it was.
+func (c Constraints) Violations(m *Map) []string {
+	var violations []string
+
+	nVars := m.VariableCount()
+	if c.MinVariables > 0 && nVars < c.MinVariables {
+		violations = append(violations, fmt.Sprintf("you returned %d variables but must return at least %d; add %d.", nVars, c.MinVariables, c.MinVariables-nVars))
+	}
+	if c.MaxVariables > 0 && nVars > c.MaxVariables {
+		violations = append(violations, fmt.Sprintf("you returned %d variables but must return at most %d; remove %d.", nVars, c.MaxVariables, nVars-c.MaxVariables))
+	}
+
+	nLoops := len(m.Loops())
+	if c.MinFeedback > 0 && nLoops < c.MinFeedback {
+		violations = append(violations, fmt.Sprintf("you returned %d feedback loops but must return at least %d; add %d.", nLoops, c.MinFeedback, c.MinFeedback-nLoops))
+	}
+	if c.MaxFeedback > 0 && nLoops > c.MaxFeedback {
+		violations = append(violations, fmt.Sprintf("you returned %d feedback loops but must return at most %d; remove %d.", nLoops, c.MaxFeedback, nLoops-c.MaxFeedback))
+	}
+
+	if missing := c.MissingVariables(m); len(missing) > 0 {
+		quoted := make([]string, 0, len(missing))
+		for _, v := range missing {
+			quoted = append(quoted, fmt.Sprintf("%q", v))
+		}
+		violations = append(violations, fmt.Sprintf("you are missing %d of the %d required variables; add %s.", len(missing), len(c.Variables), strings.Join(quoted, ", ")))
+	}
+
+	return violations
+}
+
+// BuildResponseSchema tailors the response schema to the constraints, so
+// that providers enforcing the schema enforce (some of) the constraints
+// too.  With MaxVariables n, a chain can have at most n relationships (a
+// loop through every variable), and as each chain holds at least one of
+// the n*(n-1) possible edges, there can be at most that many chains.
+func BuildResponseSchema(c Constraints) *schema.JSON {
+	s := RelationshipsResponseSchema.Clone()
+
+	chains := s.Properties["causal_chains"]
+	if c.MaxVariables > 0 {
+		maxChains := max(c.MaxVariables*(c.MaxVariables-1), 1)
+		chains.MaxItems = &maxChains
+		maxRelationships := c.MaxVariables
+		chains.Items.Properties["relationships"].MaxItems = &maxRelationships
+	}
+	if c.MinVariables > 1 || c.MinFeedback > 0 || len(c.Variables) > 0 {
+		minChains := 1
+		chains.MinItems = &minChains
+	}
+
+	return s
+}
+
+// limitChainDepth caps the number of relationships in each chain of a
+// response schema at n, keeping any lower cap already in place.
+func limitChainDepth(s *schema.JSON, n int) {
+	relationships := s.Properties["causal_chains"].Items.Properties["relationships"]
+	if relationships.MaxItems == nil || *relationships.MaxItems > n {
+		relationships.MaxItems = &n
+	}
+}
+
+// variablesSchema is the property describeVariables adds to a response
+// schema, for the model to describe each variable it uses.
+var variablesSchema = &schema.JSON{
+	Type:        schema.Array,
+	Description: "A short description of each variable in the causal chains, explaining what it measures.",
+	Items: &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name": {
+				Type:        schema.String,
+				Description: "The name of the variable, exactly as it is written in the causal chains.",
+			},
+			"description": {
+				Type:        schema.String,
+				Description: "One sentence explaining what this variable represents.",
+			},
+		},
+		Required:             []string{"name", "description"},
+		AdditionalProperties: new(bool),
+	},
+}
+
+// describeVariables asks, in a response schema, for a description of
+// each variable.
+func describeVariables(s *schema.JSON) {
+	s.Properties["variables"] = variablesSchema.Clone()
+	s.Required = append(s.Required, "variables")
+}
+
+// requireReasoning sets a minimum length on the reasoning fields of a
+// response schema.
+func requireReasoning(s *schema.JSON, minLength int) {
+	chain := s.Properties["causal_chains"].Items
+	chain.Properties["reasoning"].MinLength = &minLength
+	chain.Properties["relationships"].Items.Properties["polarity_reasoning"].MinLength = &minLength
+}
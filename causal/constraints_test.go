@@ -0,0 +1,62 @@
+package causal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+func TestBuildResponseSchema(t *testing.T) {
+	s := BuildResponseSchema(Constraints{MaxVariables: 5})
+
+	chains := s.Properties["causal_chains"]
+	require.NotNil(t, chains.MaxItems)
+	assert.Equal(t, 20, *chains.MaxItems)
+	assert.Nil(t, chains.MinItems)
+	relationships := chains.Items.Properties["relationships"]
+	require.NotNil(t, relationships.MaxItems)
+	assert.Equal(t, 5, *relationships.MaxItems)
+
+	out, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"maxItems":5`)
+
+	// the shared schema is untouched
+	assert.Nil(t, RelationshipsResponseSchema.Properties["causal_chains"].MaxItems)
+	out, err = json.Marshal(BuildResponseSchema(Constraints{}))
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "maxItems")
+}
+
+// TestResponseSchemaStrict checks the response schema is usable with
+// providers' strict modes, which require every property of every object
+// to be listed as required.
+func TestResponseSchemaStrict(t *testing.T) {
+	var check func(path string, s *schema.JSON)
+	check = func(path string, s *schema.JSON) {
+		if s.Type == schema.Object {
+			for name := range s.Properties {
+				assert.Contains(t, s.Required, name, "%s/properties/%s is not required", path, name)
+			}
+			require.NotNil(t, s.AdditionalProperties, "%s allows additional properties", path)
+			assert.False(t, *s.AdditionalProperties, "%s allows additional properties", path)
+		}
+		for name, prop := range s.Properties {
+			check(path+"/properties/"+name, prop)
+		}
+		if s.Items != nil {
+			check(path+"/items", s.Items)
+		}
+	}
+
+	check("#", BuildResponseSchema(Constraints{}))
+	check("#", BuildResponseSchema(Constraints{MinVariables: 3, MaxVariables: 5, MinFeedback: 1, Variables: []string{"Taxes"}}))
+
+	described := BuildResponseSchema(Constraints{})
+	describeVariables(described)
+	check("#", described)
+}
@@ -0,0 +1,95 @@
+package causal
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+//go:embed critique_prompt.txt
+var critiquePrompt string
+
+// CritiqueKind is the kind of problem a CritiqueSuggestion points out.
+type CritiqueKind string
+
+const (
+	// CritiqueMissingLoop is an obvious feedback loop the map lacks.
+	CritiqueMissingLoop CritiqueKind = "missing_loop"
+	// CritiqueWeakLink is a relationship that is doubtful or indirect.
+	CritiqueWeakLink CritiqueKind = "weak_link"
+	// CritiqueUnsupportedClaim is reasoning that doesn't support the
+	// relationship it is attached to.
+	CritiqueUnsupportedClaim CritiqueKind = "unsupported_claim"
+)
+
+// CritiqueSuggestion is a single problem the model found with a map.
+type CritiqueSuggestion struct {
+	Kind        CritiqueKind `json:"kind"`
+	Variables   []string     `json:"variables"`
+	Explanation string       `json:"explanation"`
+}
+
+// CritiqueReport is the model's review of a map.
+type CritiqueReport struct {
+	Suggestions []CritiqueSuggestion `json:"suggestions"`
+}
+
+// critiqueSchema is the response schema for Critique.
+var critiqueSchema = &schema.JSON{
+	Type: schema.Object,
+	Properties: map[string]*schema.JSON{
+		"suggestions": {
+			Type: schema.Array,
+			Items: &schema.JSON{
+				Type: schema.Object,
+				Properties: map[string]*schema.JSON{
+					"kind": {
+						Type: schema.String,
+						Enum: []string{string(CritiqueMissingLoop), string(CritiqueWeakLink), string(CritiqueUnsupportedClaim)},
+					},
+					"variables":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String}, Description: "The variables involved."},
+					"explanation": {Type: schema.String, Description: "What is wrong, and how it could be fixed."},
+				},
+				Required: []string{"kind", "variables", "explanation"},
+			},
+		},
+	},
+	Required: []string{"suggestions"},
+}
+
+// Critique asks the model to review m for missing obvious loops, weak
+// links and unsupported claims.  The suggestions are only reported: m is
+// not changed, and it is up to the caller to act on them, for example
+// with RefineEdge.
+func (d diagrammer) Critique(ctx context.Context, m *Map) (CritiqueReport, error) {
+	responseSchema, err := json.MarshalIndent(critiqueSchema, "", "    ")
+	if err != nil {
+		return CritiqueReport{}, fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	opts := []chat.Option{
+		chat.WithResponseFormat("critique", true, critiqueSchema),
+		chat.WithSystemPrompt(strings.ReplaceAll(critiquePrompt, "{schema}", string(responseSchema))),
+	}
+	opts = append(opts, d.samplingOptions()...)
+
+	var b strings.Builder
+	if err := m.PrettyPrint(&b); err != nil {
+		return CritiqueReport{}, err
+	}
+
+	content, _, err := d.completion(ctx, []chat.Message{{Role: chat.UserRole, Content: b.String()}}, opts)
+	if err != nil {
+		return CritiqueReport{}, err
+	}
+	var report CritiqueReport
+	if err := json.Unmarshal([]byte(content), &report); err != nil {
+		d.metrics.IncFailure(chat.FailureParse)
+		return CritiqueReport{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,31 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCritique(t *testing.T) {
+	client := &mockClient{contents: []string{`{"suggestions": [
+		{"kind": "missing_loop", "variables": ["Resistance", "Tax Burden"], "explanation": "Resistance led Britain to raise taxes further."},
+		{"kind": "weak_link", "variables": ["Clashes", "Resistance"], "explanation": "Clashes may have deterred resistance as often as inspired it."}
+	]}`}}
+
+	report, err := NewDiagrammer(client).Critique(context.Background(), testMap1)
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 1)
+	assert.Contains(t, client.calls[0][0].Content, "Clashes →(+) Resistance")
+
+	assert.Equal(t, CritiqueReport{Suggestions: []CritiqueSuggestion{
+		{Kind: CritiqueMissingLoop, Variables: []string{"Resistance", "Tax Burden"}, Explanation: "Resistance led Britain to raise taxes further."},
+		{Kind: CritiqueWeakLink, Variables: []string{"Clashes", "Resistance"}, Explanation: "Clashes may have deterred resistance as often as inspired it."},
+	}}, report)
+
+	client.contents = []string{`not json`}
+	_, err = NewDiagrammer(client).Critique(context.Background(), testMap1)
+	assert.ErrorContains(t, err, "json.Unmarshal")
+}
@@ -2,22 +2,368 @@ package causal
 
 import (
 	"context"
+	"crypto/rand"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/isee-systems/sd-ai/chat"
 	"github.com/isee-systems/sd-ai/openai"
+	"github.com/isee-systems/sd-ai/schema"
 )
 
+// Diagrammer generates causal maps with a model.  Diagrammers returned by
+// NewDiagrammer are safe for concurrent use by multiple goroutines, as
+// long as the client, metrics and post-processors they were configured
+// with are.
 type Diagrammer interface {
 	Generate(ctx context.Context, prompt, backgroundKnowledge string) (*Map, error)
+	// GenerateRaw is Generate, additionally returning the verbatim content
+	// of the model response the map was parsed from.
+	GenerateRaw(ctx context.Context, prompt, backgroundKnowledge string) (*Map, string, error)
+	// GenerateWithReport is Generate without the repair loop: instead,
+	// every problem found with the map is reported, for the caller to
+	// decide what to do.
+	GenerateWithReport(ctx context.Context, prompt, backgroundKnowledge string) (*Map, GenerationReport, error)
+	// GenerateCandidates generates n times, returning the structurally
+	// distinct maps (by Hash) in the order they were generated.
+	GenerateCandidates(ctx context.Context, prompt, backgroundKnowledge string, n int) ([]*Map, error)
+	// GenerateChunked generates a map from each chunk of background
+	// knowledge separately, and merges them, so that each request stays
+	// within the model's context window.
+	GenerateChunked(ctx context.Context, prompt string, chunks []string) (*Map, error)
+	// Append adds to the background knowledge accumulated across calls,
+	// regenerates from all of it, and merges the result into the map
+	// built up so far.
+	Append(ctx context.Context, additionalBackground string) (*Map, error)
+	// GenerateFromURL is Generate, with the readable text of the page at
+	// url (see FetchText) as the background knowledge.
+	GenerateFromURL(ctx context.Context, prompt, url string) (*Map, error)
+	// VerifyPolarities is a second-pass check of a generated map: the
+	// model reviews each relationship's polarity against its reasoning,
+	// in batches, and polarities it disagrees with are flipped and
+	// annotated.
+	VerifyPolarities(ctx context.Context, m *Map) (*Map, error)
+	// RefineEdge asks the model to reconsider the polarity and reasoning
+	// of the single relationship from -> to, given the rest of the map,
+	// without regenerating anything else.
+	RefineEdge(ctx context.Context, m *Map, from, to string) (*Map, error)
+	// Critique asks the model to review a map for missing loops, weak
+	// links and unsupported claims, reporting suggestions without
+	// applying them.
+	Critique(ctx context.Context, m *Map) (CritiqueReport, error)
 }
 
 type diagrammer struct {
-	client chat.Client
+	client              chat.Client
+	constraints         Constraints
+	maxRepairs          int
+	maxBackgroundTokens int
+	tokenizer           chat.Tokenizer
+	dryRun              bool
+	strictDecoding      bool
+	rejectAmbiguous     bool
+	requirePolarity     bool
+	describeVariables   bool
+	appendPrompt        string
+	postProcessors      []func(*Map) *Map
+	examples            []Example
+	responseSchema      *schema.JSON
+	minReasoningLength  int
+	maxChainDepth       int
+	temperature         *float64
+	seed                *int
+	deterministic       bool
+	profile             *chat.Profile
+	outputLanguage      string
+	backgroundAsSystem  bool
+	extractionMode      ExtractionMode
+	domain              string
+	currentDate         bool
+	provenanceSource    func() string
+	provenanceClock     func() time.Time
+	schemaDraft         schema.Draft
+	metrics             chat.Metrics
+
+	accumulated *accumulator
+}
+
+// accumulator is the state shared by successive calls to Append.
+type accumulator struct {
+	mu         sync.Mutex
+	background []string
+	current    *Map
+}
+
+const defaultAppendPrompt = "Please find all causal relationships in the background information."
+
+// defaultMaxBackgroundTokens leaves plenty of room in a 128k context
+// window for the system prompt and the response.
+const defaultMaxBackgroundTokens = 64 * 1024
+
+// Example is a demonstration of the expected output for some background
+// knowledge, used for few-shot prompting.
+type Example struct {
+	Background string
+	Map        *Map
+}
+
+type Option func(*diagrammer)
+
+// WithConstraints enables the repair loop: when a generated map violates
+// the constraints, the model is re-prompted with the specific gaps.
+func WithConstraints(c Constraints) Option {
+	return func(d *diagrammer) {
+		d.constraints = c
+	}
+}
+
+// WithMaxRepairs bounds the number of times the model is re-prompted after
+// a constraint violation.
+func WithMaxRepairs(n int) Option {
+	return func(d *diagrammer) {
+		d.maxRepairs = n
+	}
+}
+
+// WithDryRun makes Generate assemble and validate everything it would
+// send to the model without actually sending it.  The returned map is empty
+// and has its DryRun report set.
+func WithDryRun() Option {
+	return func(d *diagrammer) {
+		d.dryRun = true
+	}
+}
+
+// WithStrictDecoding makes it an error for the model's response to contain
+// fields the response schema doesn't define, rather than silently
+// ignoring them.
+func WithStrictDecoding() Option {
+	return func(d *diagrammer) {
+		d.strictDecoding = true
+	}
+}
+
+// WithRejectAmbiguousPolarity drops relationships the model gave an
+// unknown ("?") polarity, keeping only definite causal links.  The model
+// is also encouraged to mark ambiguous links that way rather than guess.
+func WithRejectAmbiguousPolarity() Option {
+	return func(d *diagrammer) {
+		d.rejectAmbiguous = true
+	}
+}
+
+// WithRequirePolarityReasoning drops relationships the model gave no
+// polarity reasoning for, as their sign can't be trusted.
+func WithRequirePolarityReasoning() Option {
+	return func(d *diagrammer) {
+		d.requirePolarity = true
+	}
+}
+
+// WithVariableDescriptions asks the model for a one-sentence description
+// of each variable, returned in the map's Descriptions.  It costs extra
+// output tokens on every generation, so it is off by default.
+func WithVariableDescriptions() Option {
+	return func(d *diagrammer) {
+		d.describeVariables = true
+	}
+}
+
+// WithExamples adds demonstrations to the start of the conversation: for
+// each, the background is sent as a user message, followed by the map as
+// if the model had responded with it.
+func WithExamples(examples []Example) Option {
+	return func(d *diagrammer) {
+		d.examples = append(d.examples, examples...)
+	}
+}
+
+// WithMinReasoningLength requires, in the response schema, at least n
+// characters of reasoning for each chain and relationship.  Providers
+// that enforce the schema then can't return empty reasoning.
+func WithMinReasoningLength(n int) Option {
+	return func(d *diagrammer) {
+		d.minReasoningLength = n
+	}
+}
+
+// WithMaxChainDepth keeps maps shallow: the response schema caps chains
+// at n relationships, and longer chains the model returns anyway are
+// truncated after n.  A chain that loops back on itself is instead split
+// into pieces of at most n relationships, so the loop isn't lost.
+func WithMaxChainDepth(n int) Option {
+	return func(d *diagrammer) {
+		d.maxChainDepth = n
+	}
+}
+
+// WithMetrics reports each generation's latency and failures.  m is
+// called from every goroutine generating with the diagrammer.
+func WithMetrics(m chat.Metrics) Option {
+	return func(d *diagrammer) {
+		d.metrics = m
+	}
+}
+
+// DeterministicSeed is the seed WithDeterminism samples with.
+const DeterministicSeed = 42
+
+// WithDeterminism bundles everything that makes generation reproducible:
+// sampling at temperature 0 with a fixed seed, and strict decoding so
+// that drift in the model's output is an error rather than silently
+// ignored.  No reasoning effort is requested, as reasoning adds variance.
+//
+// Determinism is only as good as the provider's support for it: Ollama
+// honors the seed, OpenAI treats it as best-effort (responses report a
+// system_fingerprint that changes when results may differ), and providers
+// without seed support fall back to temperature 0 alone.
+func WithDeterminism() Option {
+	return func(d *diagrammer) {
+		temperature := 0.0
+		seed := DeterministicSeed
+		d.temperature = &temperature
+		d.seed = &seed
+		d.deterministic = true
+		d.strictDecoding = true
+	}
+}
+
+// WithProfile sends every request with the profile's settings, which take
+// precedence over the diagrammer's defaults (but not over
+// WithDeterminism).
+func WithProfile(p chat.Profile) Option {
+	return func(d *diagrammer) {
+		d.profile = &p
+	}
+}
+
+// WithOutputLanguage asks the model to write the title, explanation and
+// reasoning in lang (e.g. "Spanish"), while keeping variable names in the
+// language of the background knowledge and prompt.
+func WithOutputLanguage(lang string) Option {
+	return func(d *diagrammer) {
+		d.outputLanguage = lang
+	}
+}
+
+// WithBackgroundAsSystem sends the background knowledge as part of the
+// system prompt rather than as a user message, for models that weight the
+// system prompt more heavily.
+func WithBackgroundAsSystem() Option {
+	return func(d *diagrammer) {
+		d.backgroundAsSystem = true
+	}
+}
+
+// ExtractionMode is how far the model may go beyond what the background
+// knowledge says.
+type ExtractionMode int
+
+const (
+	// ExtractionDefault leaves it to the model's judgment.
+	ExtractionDefault ExtractionMode = iota
+	// ExtractionConservative only allows relationships the background
+	// knowledge explicitly states, for faithfully translating a text
+	// into a diagram.
+	ExtractionConservative
+	// ExtractionExploratory encourages relationships inferred from the
+	// model's own knowledge, for explaining how a system works.
+	ExtractionExploratory
+)
+
+// WithExtractionMode adds instructions for the given mode to the system
+// prompt.
+func WithExtractionMode(mode ExtractionMode) Option {
+	return func(d *diagrammer) {
+		d.extractionMode = mode
+	}
+}
+
+// WithDomain frames the request as being from the given domain (e.g.
+// "medicine" or "macroeconomics"), so the model draws on that field's
+// vocabulary and established mechanisms.
+func WithDomain(domain string) Option {
+	return func(d *diagrammer) {
+		d.domain = domain
+	}
+}
+
+// WithCurrentDate tells the model today's date, for prompts about recent
+// or unfolding events.  The system prompt then changes from day to day,
+// so results are no longer reproducible across days, even with
+// WithDeterminism.
+func WithCurrentDate() Option {
+	return func(d *diagrammer) {
+		d.currentDate = true
+	}
+}
+
+// WithProvenance stamps every generated relationship with the name of the
+// generation it came from and when (see StampProvenance), for auditing
+// which generation contributed each link to a map built up with Append.
+// source is called once per generation for its name, and now for the
+// time; if nil, a random name and time.Now are used.  Stamped maps differ
+// from run to run unless both are deterministic.
+func WithProvenance(source func() string, now func() time.Time) Option {
+	return func(d *diagrammer) {
+		if source == nil {
+			source = func() string { return "generation " + rand.Text()[:8] }
+		}
+		if now == nil {
+			now = time.Now
+		}
+		d.provenanceSource = source
+		d.provenanceClock = now
+	}
+}
+
+// WithSchemaDraft sets the JSON Schema draft the response schema
+// declares with $schema, for providers that require a particular one.
+// By default it is draft-07.
+func WithSchemaDraft(draft schema.Draft) Option {
+	return func(d *diagrammer) {
+		d.schemaDraft = draft
+	}
+}
+
+// WithMaxBackgroundTokens sets the size above which background knowledge
+// is reported as too large in a dry run.
+func WithMaxBackgroundTokens(n int) Option {
+	return func(d *diagrammer) {
+		d.maxBackgroundTokens = n
+	}
+}
+
+// WithTokenizer counts tokens with the model's own tokenizer, for an
+// accurate context budget.  Without one, counts are estimated with
+// chat.HeuristicTokenizer.
+func WithTokenizer(t chat.Tokenizer) Option {
+	return func(d *diagrammer) {
+		d.tokenizer = t
+	}
+}
+
+// WithAppendPrompt sets the prompt used when regenerating in Append.
+func WithAppendPrompt(prompt string) Option {
+	return func(d *diagrammer) {
+		d.appendPrompt = prompt
+	}
+}
+
+// WithPostProcessors adds cleanup steps that are applied, in order, to each
+// map parsed from the model's response.  They run before constraints are
+// checked, so the repair loop sees the cleaned-up map.  Concurrent
+// generations call them concurrently, each on its own map.
+func WithPostProcessors(processors ...func(*Map) *Map) Option {
+	return func(d *diagrammer) {
+		d.postProcessors = append(d.postProcessors, processors...)
+	}
 }
 
 var (
@@ -26,17 +372,260 @@ var (
 
 	//go:embed background_prompt.txt
 	backgroundPrompt string
+
+	//go:embed repair_prompt.txt
+	repairPrompt string
+
+	//go:embed language_prompt.txt
+	languagePrompt string
+
+	//go:embed conservative_prompt.txt
+	conservativePrompt string
+
+	//go:embed exploratory_prompt.txt
+	exploratoryPrompt string
+
+	//go:embed domain_prompt.txt
+	domainPrompt string
+
+	//go:embed date_prompt.txt
+	datePrompt string
+
+	//go:embed ambiguous_prompt.txt
+	ambiguousPrompt string
 )
 
 func (d diagrammer) Generate(ctx context.Context, prompt, backgroundKnowledge string) (*Map, error) {
-	schema, err := json.MarshalIndent(RelationshipsResponseSchema, "", "    ")
+	m, _, err := d.GenerateRaw(ctx, prompt, backgroundKnowledge)
+	return m, err
+}
+
+// GenerateRaw returns the content of the response that was ultimately
+// accepted; if the repair loop re-prompted the model, that is the last
+// response.  In dry-run mode no model is called and content is empty.
+func (d diagrammer) GenerateRaw(ctx context.Context, prompt, backgroundKnowledge string) (*Map, string, error) {
+	msgs, err := d.messages(prompt, backgroundKnowledge)
 	if err != nil {
-		return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+		return nil, "", err
+	}
+	sysPrompt, err := d.systemPrompt(backgroundKnowledge)
+	if err != nil {
+		return nil, "", err
 	}
 
+	if d.dryRun {
+		return &Map{DryRun: d.validate(sysPrompt, msgs, backgroundKnowledge)}, "", nil
+	}
+
+	start := time.Now()
+	defer func() { d.metrics.ObserveGeneration(time.Since(start)) }()
+
+	for attempt := 0; ; attempt++ {
+		m, content, err := d.complete(ctx, sysPrompt, msgs)
+		if err != nil {
+			return nil, "", err
+		}
+		m = d.postProcess(m)
+
+		violations := d.constraints.Violations(m)
+		if len(violations) > 0 {
+			d.metrics.IncFailure(chat.FailureValidation)
+		}
+		if len(violations) == 0 || attempt >= d.maxRepairs {
+			if d.provenanceSource != nil {
+				m.StampProvenance(d.provenanceSource(), d.provenanceClock())
+			}
+			// without background knowledge, there's nothing to cite,
+			// and every relationship would be tagged an assumption
+			if strings.TrimSpace(backgroundKnowledge) != "" {
+				m.TagEvidence(backgroundKnowledge)
+			}
+			return m, content, nil
+		}
+
+		msgs = append(msgs,
+			chat.Message{
+				Role:    chat.AssistantRole,
+				Content: content,
+			},
+			chat.Message{
+				Role:    chat.UserRole,
+				Content: buildRepairPrompt(violations),
+			},
+		)
+	}
+}
+
+func (d diagrammer) GenerateCandidates(ctx context.Context, prompt, backgroundKnowledge string, n int) ([]*Map, error) {
+	var candidates []*Map
+	seen := make(Set[string])
+	for range n {
+		m, err := d.Generate(ctx, prompt, backgroundKnowledge)
+		if err != nil {
+			return nil, err
+		}
+		if h := m.Hash(); !seen.Contains(h) {
+			seen.Add(h)
+			candidates = append(candidates, m)
+		}
+	}
+	return candidates, nil
+}
+
+func (d diagrammer) GenerateFromURL(ctx context.Context, prompt, url string) (*Map, error) {
+	background, err := FetchText(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("FetchText(%q): %w", url, err)
+	}
+
+	return d.Generate(ctx, prompt, background)
+}
+
+func (d diagrammer) GenerateChunked(ctx context.Context, prompt string, chunks []string) (*Map, error) {
+	maps := make([]*Map, 0, len(chunks))
+	for i, chunk := range chunks {
+		m, err := d.Generate(ctx, prompt, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", i, err)
+		}
+		maps = append(maps, m)
+	}
+
+	return Merge(maps, MergeOptions{}), nil
+}
+
+func (d diagrammer) postProcess(m *Map) *Map {
+	rateLimit := m.RateLimit
+	if d.rejectAmbiguous {
+		m = dropRelationships(m, func(r RelationshipEntry) bool {
+			return isAmbiguousPolarity(r.Polarity)
+		})
+	}
+	if d.requirePolarity {
+		m = dropRelationships(m, func(r RelationshipEntry) bool {
+			return strings.TrimSpace(r.PolarityReasoning) == ""
+		})
+	}
+	if d.maxChainDepth > 0 {
+		m = truncateChains(m, d.maxChainDepth)
+	}
+	for _, process := range d.postProcessors {
+		m = process(m)
+	}
+	if m.RateLimit == nil {
+		m.RateLimit = rateLimit
+	}
+	return m
+}
+
+// dropRelationships removes the relationships drop reports true for,
+// splitting the chains they were in two.
+func dropRelationships(m *Map, drop func(RelationshipEntry) bool) *Map {
+	var chains []Chain
+	for _, c := range m.CausalChains {
+		current := Chain{InitialVariable: c.InitialVariable, Reasoning: c.Reasoning}
+		for _, r := range c.Relationships {
+			if drop(r) {
+				if len(current.Relationships) > 0 {
+					chains = append(chains, current)
+				}
+				current = Chain{InitialVariable: r.Variable, Reasoning: c.Reasoning}
+				continue
+			}
+			current.Relationships = append(current.Relationships, r)
+		}
+		if len(current.Relationships) > 0 {
+			chains = append(chains, current)
+		}
+	}
+	m.CausalChains = chains
+	return m
+}
+
+// truncateChains cuts chains with more than n relationships down to n, or,
+// if the chain revisits a variable (closing a loop), splits it into pieces
+// of at most n relationships so every link is kept.
+func truncateChains(m *Map, n int) *Map {
+	var chains []Chain
+	for _, c := range m.CausalChains {
+		if len(c.Relationships) <= n {
+			chains = append(chains, c)
+			continue
+		}
+
+		visited := NewSet(normalizeVariable(c.InitialVariable))
+		loops := false
+		for _, r := range c.Relationships {
+			if v := normalizeVariable(r.Variable); visited.Contains(v) {
+				loops = true
+			} else {
+				visited.Add(v)
+			}
+		}
+
+		if !loops {
+			c.Relationships = c.Relationships[:n]
+			chains = append(chains, c)
+			continue
+		}
+		for start := 0; start < len(c.Relationships); start += n {
+			piece := Chain{InitialVariable: c.InitialVariable, Reasoning: c.Reasoning}
+			if start > 0 {
+				piece.InitialVariable = c.Relationships[start-1].Variable
+			}
+			piece.Relationships = c.Relationships[start:min(start+n, len(c.Relationships))]
+			chains = append(chains, piece)
+		}
+	}
+	m.CausalChains = chains
+	return m
+}
+
+func (d diagrammer) Append(ctx context.Context, additionalBackground string) (*Map, error) {
+	acc := d.accumulated
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	background := append(slices.Clone(acc.background), additionalBackground)
+
+	m, err := d.Generate(ctx, d.appendPrompt, strings.Join(background, "\n\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	if acc.current != nil {
+		merged := Merge([]*Map{acc.current, m}, MergeOptions{})
+		merged.Title, merged.Explanation = m.Title, m.Explanation
+		m = merged
+	}
+
+	acc.background = background
+	acc.current = m
+
+	return m, nil
+}
+
+func (d diagrammer) messages(prompt, backgroundKnowledge string) ([]chat.Message, error) {
 	var msgs []chat.Message
 
-	if backgroundKnowledge != "" {
+	for _, example := range d.examples {
+		content, err := json.Marshal(example.Map)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		msgs = append(msgs,
+			chat.Message{
+				Role:    chat.UserRole,
+				Content: strings.ReplaceAll(backgroundPrompt, "{backgroundKnowledge}", example.Background) + "\n\n" + defaultAppendPrompt,
+			},
+			chat.Message{
+				Role:    chat.AssistantRole,
+				Content: string(content),
+			},
+		)
+	}
+
+	if backgroundKnowledge != "" && !d.backgroundAsSystem {
 		msgs = append(msgs, chat.Message{
 			Role:    chat.UserRole,
 			Content: strings.ReplaceAll(backgroundPrompt, "{backgroundKnowledge}", backgroundKnowledge),
@@ -48,37 +637,177 @@ func (d diagrammer) Generate(ctx context.Context, prompt, backgroundKnowledge st
 		Content: prompt,
 	})
 
-	response, err := d.client.ChatCompletion(ctx, msgs,
-		chat.WithResponseFormat("relationships_response", true, RelationshipsResponseSchema),
-		chat.WithMaxTokens(64*1024),
-		chat.WithSystemPrompt(strings.ReplaceAll(systemPrompt, "{schema}", string(schema))),
-	)
+	return msgs, nil
+}
+
+// systemPrompt renders the system prompt, which includes the background
+// knowledge when sending it there.
+func (d diagrammer) systemPrompt(backgroundKnowledge string) (string, error) {
+	if err := d.responseSchema.Validate(); err != nil {
+		return "", fmt.Errorf("response schema: %w", err)
+	}
+
+	responseSchema, err := json.MarshalIndent(d.responseSchema, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	prompt := strings.ReplaceAll(systemPrompt, "{schema}", string(responseSchema))
+	if d.outputLanguage != "" {
+		prompt += "\n\n" + strings.ReplaceAll(languagePrompt, "{language}", d.outputLanguage)
+	}
+	if d.domain != "" {
+		prompt += "\n\n" + strings.ReplaceAll(domainPrompt, "{domain}", d.domain)
+	}
+	if d.currentDate {
+		prompt += "\n\n" + strings.ReplaceAll(datePrompt, "{now}", time.Now().Format("Monday, January 2, 2006"))
+	}
+	if d.rejectAmbiguous {
+		prompt += "\n\n" + ambiguousPrompt
+	}
+	switch d.extractionMode {
+	case ExtractionConservative:
+		prompt += "\n\n" + conservativePrompt
+	case ExtractionExploratory:
+		prompt += "\n\n" + exploratoryPrompt
+	}
+	if d.backgroundAsSystem && backgroundKnowledge != "" {
+		prompt += "\n\n" + strings.ReplaceAll(backgroundPrompt, "{backgroundKnowledge}", backgroundKnowledge)
+	}
+	return prompt, nil
+}
+
+// complete sends a single request to the model, returning both the parsed
+// map and the raw content the model responded with.
+func (d diagrammer) complete(ctx context.Context, sysPrompt string, msgs []chat.Message) (*Map, string, error) {
+	opts := []chat.Option{
+		chat.WithResponseFormat("relationships_response", true, d.responseSchema),
+		chat.WithMaxTokens(64 * 1024),
+		chat.WithSystemPrompt(sysPrompt),
+	}
+	opts = append(opts, d.samplingOptions()...)
+
+	content, response, err := d.completion(ctx, msgs, opts)
 	if err != nil {
-		return nil, fmt.Errorf("c.ChatCompletion: %w", err)
+		return nil, "", err
+	}
+
+	var rr Map
+	dec := json.NewDecoder(strings.NewReader(content))
+	if d.strictDecoding {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&rr); err != nil {
+		d.metrics.IncFailure(chat.FailureParse)
+		return nil, "", fmt.Errorf("json.Decode: %w", err)
+	}
+
+	if resp, ok := response.(*chat.Response); ok {
+		rr.RateLimit = &resp.RateLimit
+	}
+
+	return &rr, content, nil
+}
+
+// samplingOptions are the options controlling how the model samples its
+// response, shared by every kind of request the diagrammer makes.  They
+// come after the request's own options, so they override them.
+func (d diagrammer) samplingOptions() []chat.Option {
+	var opts []chat.Option
+	if d.profile != nil {
+		opts = append(opts, chat.WithProfile(*d.profile))
+	}
+	if d.temperature != nil {
+		opts = append(opts, chat.WithTemperature(*d.temperature))
+	}
+	if d.seed != nil {
+		opts = append(opts, chat.WithSeed(*d.seed))
+	}
+	if d.deterministic {
+		// a profile's reasoning effort would add variance back
+		opts = append(opts, chat.WithReasoningEffort(""))
+	}
+	return opts
+}
+
+// completion sends a request to the model, returning the content of its
+// first choice along with the response itself.
+func (d diagrammer) completion(ctx context.Context, msgs []chat.Message, opts []chat.Option) (string, io.Reader, error) {
+	response, err := d.client.ChatCompletion(ctx, msgs, opts...)
+	if err != nil {
+		d.metrics.IncFailure(chat.FailureHTTP)
+		return "", nil, fmt.Errorf("c.ChatCompletion: %w", err)
 	}
 
 	responseBody, err := io.ReadAll(response)
 	if err != nil {
-		return nil, fmt.Errorf("io.ReadAll: %w", err)
+		d.metrics.IncFailure(chat.FailureHTTP)
+		return "", nil, fmt.Errorf("io.ReadAll: %w", err)
 	}
 
 	var ccr openai.ChatCompletionResponse
 	if err := json.Unmarshal(responseBody, &ccr); err != nil {
-		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+		d.metrics.IncFailure(chat.FailureParse)
+		return "", nil, fmt.Errorf("json.Unmarshal: %w", err)
 	}
 
-	var rr Map
-	if err := json.Unmarshal([]byte(ccr.Choices[0].Message.Content), &rr); err != nil {
-		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	if len(ccr.Choices) == 0 {
+		d.metrics.IncFailure(chat.FailureParse)
+		return "", nil, fmt.Errorf("chat completion response has no choices")
 	}
 
-	return &rr, nil
+	if refusal := ccr.Choices[0].Message.Refusal; refusal != "" {
+		d.metrics.IncFailure(chat.FailureRefusal)
+		return "", nil, &openai.ErrModelRefused{Refusal: refusal}
+	}
+
+	return ccr.Choices[0].Message.Content, response, nil
+}
+
+func buildRepairPrompt(violations []string) string {
+	var b strings.Builder
+	for _, v := range violations {
+		b.WriteString("* ")
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+
+	return strings.ReplaceAll(repairPrompt, "{violations}", strings.TrimSpace(b.String()))
 }
 
 var _ Diagrammer = &diagrammer{}
 
-func NewDiagrammer(client chat.Client) Diagrammer {
-	return diagrammer{
-		client: client,
+// NewDiagrammer returns a diagrammer that generates with client.  Its
+// configuration is fixed once it is returned: each call builds its own
+// messages and maps, and the only state shared between calls, the
+// background accumulated by Append, is guarded by a mutex (so concurrent
+// Appends take turns).
+func NewDiagrammer(client chat.Client, opts ...Option) Diagrammer {
+	d := diagrammer{
+		client:              client,
+		maxRepairs:          2,
+		maxBackgroundTokens: defaultMaxBackgroundTokens,
+		tokenizer:           chat.HeuristicTokenizer,
+		appendPrompt:        defaultAppendPrompt,
+		accumulated:         &accumulator{},
+		metrics:             chat.NoMetrics,
+	}
+	for _, opt := range opts {
+		opt(&d)
 	}
+	d.responseSchema = BuildResponseSchema(d.constraints)
+	if d.describeVariables {
+		describeVariables(d.responseSchema)
+	}
+	if d.minReasoningLength > 0 {
+		requireReasoning(d.responseSchema, d.minReasoningLength)
+	}
+	if d.maxChainDepth > 0 {
+		limitChainDepth(d.responseSchema, d.maxChainDepth)
+	}
+	if d.schemaDraft != "" {
+		d.responseSchema.Schema = string(d.schemaDraft)
+	}
+
+	return d
 }
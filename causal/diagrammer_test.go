@@ -1,20 +1,79 @@
 package causal
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/openai"
+	"github.com/isee-systems/sd-ai/schema"
 )
 
+// mockClient replays canned model content, one entry per call (repeating
+// the last one), and records the messages it was sent.
+type mockClient struct {
+	mu        sync.Mutex
+	contents  []string
+	rateLimit *chat.RateLimitInfo
+	refusal   string
+	calls     [][]chat.Message
+	options   []chat.Options
+}
+
+var _ chat.Client = &mockClient{}
+
+func (c *mockClient) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ...chat.Option) (io.Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = append(c.calls, slices.Clone(msgs))
+	c.options = append(c.options, chat.ApplyOptions(opts...))
+	content := c.contents[min(len(c.calls), len(c.contents))-1]
+
+	var ccr openai.ChatCompletionResponse
+	ccr.Choices = make([]openai.ChatCompletionChoice, 1)
+	ccr.Choices[0].Message.Role = chat.AssistantRole
+	ccr.Choices[0].Message.Content = content
+	ccr.Choices[0].Message.Refusal = c.refusal
+
+	body, err := json.Marshal(ccr)
+	if err != nil {
+		return nil, err
+	}
+	if c.rateLimit != nil {
+		return &chat.Response{
+			Reader:     strings.NewReader(string(body)),
+			StatusCode: 200,
+			RateLimit:  *c.rateLimit,
+		}, nil
+	}
+	return strings.NewReader(string(body)), nil
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
 var testMap1 = &Map{
 	Title:       "American Revolution Onset",
 	Explanation: "Based on historical context and user input,",
-	Relationships: []Relationship{
+	CausalChains: NewMap([]Relationship{
 		{
 			From:              "Tax Burden",
 			To:                "Tensions",
@@ -64,7 +123,7 @@ var testMap1 = &Map{
 			Reasoning:         "As tensions rose, the British government responded with stricter enforcement of its authority and additional taxation measures, aiming to quell dissent and maintain control.",
 			PolarityReasoning: "Increased Tensions led to increased Tax Burden as Britain attempted to assert its control over the colonies more firmly.",
 		},
-	},
+	}).CausalChains,
 }
 
 var roadRage1 = `{
@@ -135,30 +194,126 @@ func TestExtractingResults(t *testing.T) {
 
 	vars := causalMap.Variables()
 	expectedVars := NewSet(
-		"Tax Burden",
-		"Resistance",
-		"Clashes",
-		"Tensions",
+		"tax burden",
+		"resistance",
+		"clashes",
+		"tensions",
 	)
 	assert.Equal(t, expectedVars, vars)
 
 	loops := causalMap.Loops()
-	assert.Contains(t, loops, []string{"Clashes", "Tensions", "Clashes"})
-	assert.Contains(t, loops, []string{"Clashes", "Resistance", "Clashes"})
-	assert.Contains(t, loops, []string{"Tax Burden", "Tensions", "Tax Burden"})
-	assert.Contains(t, loops, []string{"Clashes", "Tensions", "Tax Burden", "Resistance", "Clashes"})
+	assert.Contains(t, loops, []string{"clashes", "tensions", "clashes"})
+	assert.Contains(t, loops, []string{"clashes", "resistance", "clashes"})
+	assert.Contains(t, loops, []string{"tax burden", "tensions", "tax burden"})
+	assert.Contains(t, loops, []string{"clashes", "tensions", "tax burden", "resistance", "clashes"})
 	assert.Equal(t, 4, len(loops))
 }
 
+func TestVariablesInOrder(t *testing.T) {
+	assert.Equal(t, []string{"tax burden", "tensions", "resistance", "clashes"}, testMap1.VariablesInOrder())
+}
+
+func TestStripReasoning(t *testing.T) {
+	stripped := testMap1.StripReasoning()
+	for _, r := range stripped.Relationships() {
+		assert.Empty(t, r.Reasoning)
+		assert.Empty(t, r.PolarityReasoning)
+	}
+	assert.Equal(t, testMap1.Variables(), stripped.Variables())
+	assert.Equal(t, testMap1.Loops(), stripped.Loops())
+	assert.NotEmpty(t, testMap1.Relationships()[0].PolarityReasoning)
+}
+
+func TestHashIgnoresArrangement(t *testing.T) {
+	rechained := NewMap(testMap1.Relationships()).Minimal().StripReasoning()
+	assert.Less(t, len(rechained.CausalChains), len(testMap1.CausalChains))
+	assert.Equal(t, testMap1.Hash(), rechained.Hash())
+
+	flipped := NewMap(testMap1.Relationships())
+	flipped.CausalChains[0].Relationships[0].Polarity = "-"
+	assert.NotEqual(t, testMap1.Hash(), flipped.Hash())
+}
+
+func TestCounts(t *testing.T) {
+	vars := make(Set[string])
+	edges := make(Set[string])
+	for _, r := range testMap1.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		vars.Add(from)
+		vars.Add(to)
+		edges.Add(from + " -> " + to)
+	}
+
+	assert.Equal(t, len(vars), testMap1.VariableCount())
+	assert.Equal(t, len(edges), testMap1.EdgeCount())
+}
+
+func TestNewMapCoalescesPaths(t *testing.T) {
+	rels := []Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "clashes", To: "Tax Burden", Polarity: "-"},
+	}
+
+	m := NewMap(rels)
+	require.Len(t, m.CausalChains, 1)
+	assert.Equal(t, "Tax Burden", m.CausalChains[0].InitialVariable)
+	assert.Len(t, m.CausalChains[0].Relationships, 3)
+	assert.Equal(t, [][]string{{"clashes", "tax burden", "tensions", "clashes"}}, m.Loops())
+
+	// one chain per relationship has the same loops
+	var fragmented Map
+	for _, r := range rels {
+		fragmented.CausalChains = append(fragmented.CausalChains, NewMap([]Relationship{r}).CausalChains...)
+	}
+	assert.Len(t, fragmented.CausalChains, 3)
+	assert.Equal(t, fragmented.Loops(), m.Loops())
+}
+
+func TestParseMapShapes(t *testing.T) {
+	chains, err := ParseMap([]byte(mustJSON(t, testMap1)))
+	require.NoError(t, err)
+
+	flat, err := ParseMap([]byte(mustJSON(t, map[string]any{
+		"title":         testMap1.Title,
+		"explanation":   testMap1.Explanation,
+		"relationships": testMap1.Relationships(),
+	})))
+	require.NoError(t, err)
+
+	assert.Equal(t, testMap1.Title, flat.Title)
+	assert.Equal(t, chains.Variables(), flat.Variables())
+	assert.Equal(t, chains.Loops(), flat.Loops())
+	assert.Equal(t, chains.Relationships(), flat.Relationships())
+
+	roadRage, err := ParseMap([]byte(roadRage1))
+	require.NoError(t, err)
+	assert.Len(t, roadRage.Relationships(), 8)
+	assert.Equal(t, [][]string{{"aggressive driving behaviors", "road rage incidents", "aggressive driving behaviors"}}, roadRage.Loops())
+
+	// the flat shape keeps each relationship's reasoning
+	flat, err = ParseMap([]byte(mustJSON(t, map[string]any{
+		"title":         roadRage.Title,
+		"explanation":   roadRage.Explanation,
+		"relationships": roadRage.Relationships(),
+	})))
+	require.NoError(t, err)
+	assert.NotEmpty(t, flat.Relationships()[0].Reasoning)
+	assert.Equal(t, roadRage.Relationships(), flat.Relationships())
+}
+
 func TestDiagrammerSVG(t *testing.T) {
-	var causalMap Map
-	err := json.Unmarshal([]byte(roadRage1), &causalMap)
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz dot not installed")
+	}
+
+	causalMap, err := ParseMap([]byte(roadRage1))
 	require.NoError(t, err)
 
 	loops := causalMap.Loops()
 	assert.NotEmpty(t, loops)
 
-	svg, err := causalMap.VisualSVG()
+	svg, err := causalMap.VisualSVG(context.Background())
 	require.NoError(t, err)
 
 	// assert we got something
@@ -177,3 +332,642 @@ func TestDiagrammerSVG(t *testing.T) {
 	err = exec.Command("open", path).Run()
 	require.NoError(t, err)
 }
+
+func TestRepairPromptQuantifiesGap(t *testing.T) {
+	small := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, testMap1), mustJSON(t, small)}}
+
+	d := NewDiagrammer(client, WithConstraints(Constraints{MaxVariables: 2}))
+	result, err := d.Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, NewSet("tax burden", "tensions"), result.Variables())
+
+	require.Len(t, client.calls, 2)
+	repair := client.calls[1]
+	require.Len(t, repair, 3)
+	assert.Equal(t, chat.AssistantRole, repair[1].Role)
+	assert.Equal(t, chat.UserRole, repair[2].Role)
+	assert.Contains(t, repair[2].Content, "you returned 4 variables but must return at most 2; remove 2.")
+}
+
+func TestRepairGivesUpAfterMaxRepairs(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	d := NewDiagrammer(client,
+		WithConstraints(Constraints{MinFeedback: 6, Variables: []string{"Taxation"}}),
+		WithMaxRepairs(1),
+	)
+	result, err := d.Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	require.Len(t, client.calls, 2)
+	repair := client.calls[1][2].Content
+	assert.Contains(t, repair, "you returned 4 feedback loops but must return at least 6; add 2.")
+	assert.Contains(t, repair, `you are missing 1 of the 1 required variables; add "Taxation".`)
+}
+
+func TestGenerateExposesRateLimit(t *testing.T) {
+	client := &mockClient{
+		contents:  []string{mustJSON(t, testMap1)},
+		rateLimit: &chat.RateLimitInfo{RemainingRequests: 7, RemainingTokens: 1234},
+	}
+
+	result, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	require.NotNil(t, result.RateLimit)
+	assert.Equal(t, 7, result.RateLimit.RemainingRequests)
+	assert.Equal(t, 1234, result.RateLimit.RemainingTokens)
+}
+
+func TestGenerateRawReturnsContent(t *testing.T) {
+	small := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	content := mustJSON(t, small)
+	client := &mockClient{contents: []string{mustJSON(t, testMap1), content}}
+
+	d := NewDiagrammer(client, WithConstraints(Constraints{MaxVariables: 2}))
+	result, raw, err := d.GenerateRaw(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, content, raw)
+	assert.Equal(t, NewSet("tax burden", "tensions"), result.Variables())
+}
+
+func TestStrictDecoding(t *testing.T) {
+	content := `{"title": "t", "explanation": "e", "confidence": 0.9, "causal_chains": [` +
+		`{"initial_variable": "Tax Burden", "reasoning": "r", "relationships": [` +
+		`{"variable": "Tensions", "polarity": "+", "polarity_reasoning": "p"}]}]}`
+	client := &mockClient{contents: []string{content}}
+
+	result, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, "p", result.CausalChains[0].Relationships[0].PolarityReasoning)
+
+	_, err = NewDiagrammer(client, WithStrictDecoding()).Generate(context.Background(), "explain the revolution", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "confidence"`)
+
+	client = &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	_, err = NewDiagrammer(client, WithStrictDecoding()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+}
+
+func TestRejectAmbiguousPolarity(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Weather", Polarity: "?"},
+		{From: "Weather", To: "Clashes", Polarity: "-"},
+		{From: "Clashes", To: "Tensions", Polarity: "unknown"},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+
+	result, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, m.Relationships(), result.Relationships())
+
+	result, err = NewDiagrammer(client, WithRejectAmbiguousPolarity()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, []Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Weather", To: "Clashes", Polarity: "-"},
+	}, result.Relationships())
+
+	// "?" is always allowed, but only encouraged when it will be rejected
+	polarity := func(opts chat.Options) *schema.JSON {
+		return opts.ResponseFormat.Schema.Properties["causal_chains"].Items.Properties["relationships"].Items.Properties["polarity"]
+	}
+	require.Len(t, client.options, 2)
+	for _, opts := range client.options {
+		assert.Equal(t, []string{"+", "-", "?"}, polarity(opts).Enum)
+	}
+	assert.NotContains(t, client.options[0].SystemPrompt, `unknown ("?") polarity`)
+	assert.Contains(t, client.options[1].SystemPrompt, `unknown ("?") polarity`)
+}
+
+func TestWithVariableDescriptions(t *testing.T) {
+	m := NewMap(testMap1.Relationships())
+	m.Descriptions = []VariableDescription{{Name: "Tax Burden", Description: "The cost of British taxes to colonists."}}
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+
+	_, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	result, err := NewDiagrammer(client, WithVariableDescriptions(), WithStrictDecoding()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"tax burden": "The cost of British taxes to colonists."}, result.VariableDescriptions())
+
+	// descriptions cost tokens, so they're only asked for with the option
+	require.Len(t, client.options, 2)
+	plain, described := client.options[0].ResponseFormat.Schema, client.options[1].ResponseFormat.Schema
+	assert.NotContains(t, plain.Properties, "variables")
+	assert.NotContains(t, plain.Required, "variables")
+	assert.Contains(t, described.Properties, "variables")
+	assert.Contains(t, described.Required, "variables")
+}
+
+func TestRequirePolarityReasoning(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+", PolarityReasoning: "More taxes, more tension."},
+		{From: "Tensions", To: "Clashes", Polarity: "+", Reasoning: "Tension boils over.", PolarityReasoning: " "},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+
+	result, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.EdgeCount())
+
+	result, err = NewDiagrammer(client, WithRequirePolarityReasoning()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.EdgeCount())
+	assert.Equal(t, []string{"tensions"}, result.Influences("Tax Burden"))
+	assert.Empty(t, result.Influences("Tensions"))
+}
+
+func TestConcurrentGenerate(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	d := NewDiagrammer(client,
+		WithConstraints(Constraints{MinVariables: 2}),
+		WithRejectAmbiguousPolarity(),
+		WithRequirePolarityReasoning(),
+		WithExamples([]Example{{Background: "More rabbits have more babies.", Map: testMap1}}),
+	)
+
+	const n = 32
+	var wg sync.WaitGroup
+	results := make([]*Map, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = d.Generate(context.Background(), "explain the revolution", fmt.Sprintf("background %d", i))
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		require.NoError(t, errs[i])
+		assert.Equal(t, testMap1.Hash(), results[i].Hash())
+	}
+	assert.Len(t, client.calls, n)
+}
+
+func TestProfile(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	temperature := 0.7
+	profile := chat.Profile{
+		Name:            "reasoner",
+		Temperature:     &temperature,
+		ReasoningEffort: "high",
+		MaxTokens:       8192,
+	}
+
+	_, err := NewDiagrammer(client, WithProfile(profile)).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client, WithProfile(profile), WithDeterminism()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	opts := client.options[0]
+	require.NotNil(t, opts.Temperature)
+	assert.Equal(t, 0.7, *opts.Temperature)
+	assert.Equal(t, "high", opts.ReasoningEffort)
+	assert.Equal(t, 8192, opts.MaxTokens)
+	assert.Nil(t, opts.Seed)
+	assert.NotNil(t, opts.ResponseFormat)
+
+	// determinism still wins
+	opts = client.options[1]
+	require.NotNil(t, opts.Temperature)
+	assert.Equal(t, 0.0, *opts.Temperature)
+	assert.Empty(t, opts.ReasoningEffort)
+	assert.Equal(t, 8192, opts.MaxTokens)
+}
+
+func TestOutputLanguage(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client, WithOutputLanguage("Spanish")).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	assert.Contains(t, client.options[0].SystemPrompt, "Write the title, explanation, reasoning and polarity_reasoning fields in Spanish.")
+	assert.NotContains(t, client.options[1].SystemPrompt, "Spanish")
+}
+
+func TestDomainAndDate(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client, WithDomain("public health"), WithCurrentDate()).Generate(context.Background(), "explain the outbreak", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client).Generate(context.Background(), "explain the outbreak", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	assert.Contains(t, client.options[0].SystemPrompt, "in the field of public health.")
+	assert.Contains(t, client.options[0].SystemPrompt, "Today's date is ")
+	assert.NotContains(t, client.options[1].SystemPrompt, "public health")
+	assert.NotContains(t, client.options[1].SystemPrompt, "Today's date is ")
+}
+
+func TestSchemaDraft(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client, WithSchemaDraft(schema.Draft202012)).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", client.options[0].ResponseFormat.Schema.Schema)
+	assert.Contains(t, client.options[0].SystemPrompt, `"$schema": "https://json-schema.org/draft/2020-12/schema"`)
+	assert.Equal(t, schema.URL, client.options[1].ResponseFormat.Schema.Schema)
+
+	_, err = NewDiagrammer(client, WithSchemaDraft("draft-03")).Generate(context.Background(), "explain the revolution", "")
+	assert.ErrorContains(t, err, `unsupported $schema "draft-03"`)
+	assert.Len(t, client.calls, 2)
+}
+
+func TestExtractionMode(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	for _, mode := range []ExtractionMode{ExtractionDefault, ExtractionConservative, ExtractionExploratory} {
+		_, err := NewDiagrammer(client, WithExtractionMode(mode)).Generate(context.Background(), "explain the revolution", "")
+		require.NoError(t, err)
+	}
+
+	require.Len(t, client.options, 3)
+	assert.NotContains(t, client.options[0].SystemPrompt, conservativePrompt)
+	assert.NotContains(t, client.options[0].SystemPrompt, exploratoryPrompt)
+	assert.Contains(t, client.options[1].SystemPrompt, "Only include causal relationships that the background information explicitly states")
+	assert.NotContains(t, client.options[1].SystemPrompt, exploratoryPrompt)
+	assert.Contains(t, client.options[2].SystemPrompt, "infer")
+	assert.NotContains(t, client.options[2].SystemPrompt, conservativePrompt)
+}
+
+func TestBackgroundAsSystem(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	background := "The Stamp Act raised taxes, which caused tension."
+
+	_, err := NewDiagrammer(client, WithBackgroundAsSystem()).Generate(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client).Generate(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 2)
+	assert.Contains(t, client.options[0].SystemPrompt, background)
+	require.Len(t, client.calls[0], 1)
+	assert.Equal(t, "explain the revolution", client.calls[0][0].Content)
+
+	assert.NotContains(t, client.options[1].SystemPrompt, background)
+	require.Len(t, client.calls[1], 2)
+	assert.Contains(t, client.calls[1][0].Content, background)
+}
+
+func TestExamplesPrecedeRequest(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	example := NewMap([]Relationship{
+		{From: "Births", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Births", Polarity: "+"},
+	})
+
+	d := NewDiagrammer(client, WithExamples([]Example{
+		{Background: "More rabbits have more babies.", Map: example},
+	}))
+	_, err := d.Generate(context.Background(), "explain the revolution", "taxes caused tension.")
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 1)
+	msgs := client.calls[0]
+	require.Len(t, msgs, 4)
+	assert.Equal(t, chat.UserRole, msgs[0].Role)
+	assert.Contains(t, msgs[0].Content, "More rabbits have more babies.")
+	assert.Equal(t, chat.AssistantRole, msgs[1].Role)
+	assert.Equal(t, mustJSON(t, example), msgs[1].Content)
+	assert.Contains(t, msgs[2].Content, "taxes caused tension.")
+	assert.Equal(t, "explain the revolution", msgs[3].Content)
+}
+
+func TestDeterminismBundle(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client, WithDeterminism()).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	plain, deterministic := client.options[0], client.options[1]
+	assert.Nil(t, plain.Temperature)
+	assert.Nil(t, plain.Seed)
+
+	require.NotNil(t, deterministic.Temperature)
+	assert.Equal(t, 0.0, *deterministic.Temperature)
+	require.NotNil(t, deterministic.Seed)
+	assert.Equal(t, DeterministicSeed, *deterministic.Seed)
+	assert.Empty(t, deterministic.ReasoningEffort)
+
+	client = &mockClient{contents: []string{`{"title": "t", "unexpected": true}`}}
+	_, err = NewDiagrammer(client, WithDeterminism()).Generate(context.Background(), "explain the revolution", "")
+	assert.ErrorContains(t, err, `unknown field "unexpected"`)
+}
+
+func TestMinReasoningLength(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client, WithMinReasoningLength(20)).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	_, err = NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, client.options, 2)
+	chain := client.options[0].ResponseFormat.Schema.Properties["causal_chains"].Items
+	require.NotNil(t, chain.Properties["reasoning"].MinLength)
+	assert.Equal(t, 20, *chain.Properties["reasoning"].MinLength)
+	polarityReasoning := chain.Properties["relationships"].Items.Properties["polarity_reasoning"]
+	require.NotNil(t, polarityReasoning.MinLength)
+	assert.Equal(t, 20, *polarityReasoning.MinLength)
+
+	out, err := json.Marshal(client.options[1].ResponseFormat.Schema)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "minLength")
+}
+
+func TestMaxChainDepth(t *testing.T) {
+	m := &Map{CausalChains: []Chain{
+		{
+			InitialVariable: "Tax Burden",
+			Relationships: []RelationshipEntry{
+				{Variable: "Tensions", Polarity: "+"},
+				{Variable: "Protests", Polarity: "+"},
+				{Variable: "Clashes", Polarity: "+"},
+				{Variable: "Casualties", Polarity: "+"},
+			},
+		},
+		{
+			InitialVariable: "Clashes",
+			Relationships: []RelationshipEntry{
+				{Variable: "Resistance", Polarity: "+"},
+				{Variable: "Crackdowns", Polarity: "+"},
+				{Variable: "Clashes", Polarity: "+"},
+			},
+		},
+	}}
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+
+	result, err := NewDiagrammer(client, WithMaxChainDepth(2)).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	require.Len(t, result.CausalChains, 3)
+	for _, c := range result.CausalChains {
+		assert.LessOrEqual(t, len(c.Relationships), 2)
+	}
+	assert.Equal(t, []string{"tensions"}, result.Influences("Tax Burden"))
+	assert.Empty(t, result.Influences("Protests"))
+	assert.False(t, result.Variables().Contains("casualties"))
+	assert.Equal(t, [][]string{{"clashes", "resistance", "crackdowns", "clashes"}}, result.Loops())
+
+	relationships := client.options[0].ResponseFormat.Schema.Properties["causal_chains"].Items.Properties["relationships"]
+	require.NotNil(t, relationships.MaxItems)
+	assert.Equal(t, 2, *relationships.MaxItems)
+}
+
+func TestGenerateCandidatesDedups(t *testing.T) {
+	small := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	rechained := NewMap(testMap1.Relationships()).Minimal()
+	client := &mockClient{contents: []string{mustJSON(t, testMap1), mustJSON(t, small), mustJSON(t, rechained)}}
+
+	candidates, err := NewDiagrammer(client).GenerateCandidates(context.Background(), "explain the revolution", "", 3)
+	require.NoError(t, err)
+	assert.Len(t, client.calls, 3)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, testMap1.Hash(), candidates[0].Hash())
+	assert.Equal(t, small.Hash(), candidates[1].Hash())
+}
+
+// fakeMetrics counts the events it receives.
+type fakeMetrics struct {
+	generations int
+	failures    map[chat.FailureKind]int
+}
+
+func (m *fakeMetrics) ObserveGeneration(time.Duration)  { m.generations++ }
+func (m *fakeMetrics) IncFailure(kind chat.FailureKind) { m.failures[kind]++ }
+func (m *fakeMetrics) IncRetry()                        {}
+
+func TestMetrics(t *testing.T) {
+	metrics := &fakeMetrics{failures: make(map[chat.FailureKind]int)}
+	client := &mockClient{contents: []string{mustJSON(t, testMap1), "not json"}}
+
+	d := NewDiagrammer(client, WithMetrics(metrics), WithConstraints(Constraints{MaxVariables: 2}), WithMaxRepairs(1))
+	_, err := d.Generate(context.Background(), "explain the revolution", "")
+	require.Error(t, err)
+
+	assert.Equal(t, 1, metrics.generations)
+	assert.Equal(t, map[chat.FailureKind]int{
+		chat.FailureValidation: 1,
+		chat.FailureParse:      1,
+	}, metrics.failures)
+}
+
+func TestGenerateChunkedMerges(t *testing.T) {
+	first := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	second := NewMap([]Relationship{
+		{From: "tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tax Burden", Polarity: "+"},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, first), mustJSON(t, second)}}
+
+	merged, err := NewDiagrammer(client).GenerateChunked(context.Background(), "explain the revolution", []string{
+		"Taxes raised tensions.",
+		"Tensions led to clashes, and clashes to more taxes.",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 2)
+	assert.Contains(t, client.calls[0][0].Content, "Taxes raised tensions.")
+	assert.Contains(t, client.calls[1][0].Content, "Tensions led to clashes")
+
+	assert.Equal(t, NewSet("tax burden", "tensions", "clashes"), merged.Variables())
+	assert.Equal(t, [][]string{{"clashes", "tax burden", "tensions", "clashes"}}, merged.Loops())
+}
+
+func TestProvenanceSurvivesMerge(t *testing.T) {
+	first := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	second := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, first), mustJSON(t, second)}}
+
+	before := time.Now()
+	d := NewDiagrammer(client, WithProvenance(nil, nil))
+	_, err := d.Append(context.Background(), "Taxes raised tensions.")
+	require.NoError(t, err)
+	merged, err := d.Append(context.Background(), "Tensions led to clashes.")
+	require.NoError(t, err)
+
+	rels := merged.Relationships()
+	require.Len(t, rels, 2)
+	for _, r := range rels {
+		assert.True(t, strings.HasPrefix(r.Source, "generation "), r.Source)
+		assert.False(t, r.CreatedAt.Before(before))
+	}
+	assert.NotEqual(t, rels[0].Source, rels[1].Source)
+
+	data, err := json.Marshal(merged)
+	require.NoError(t, err)
+	parsed, err := ParseMap(data)
+	require.NoError(t, err)
+	assert.Equal(t, rels[0].Source, parsed.Relationships()[0].Source)
+	assert.True(t, rels[0].CreatedAt.Equal(parsed.Relationships()[0].CreatedAt))
+}
+
+func TestProvenanceOptIn(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	// by default, nothing is stamped, so results are reproducible
+	m, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	for _, r := range m.Relationships() {
+		assert.Empty(t, r.Source)
+		assert.True(t, r.CreatedAt.IsZero())
+	}
+
+	at := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	n := 0
+	d := NewDiagrammer(client, WithProvenance(func() string {
+		n++
+		return fmt.Sprintf("run %d", n)
+	}, func() time.Time { return at }))
+	m, err = d.Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	for _, r := range m.Relationships() {
+		assert.Equal(t, "run 1", r.Source)
+		assert.Equal(t, at, r.CreatedAt)
+	}
+
+	// Relationship and RelationshipEntry spell the field the same way
+	assert.Contains(t, mustJSON(t, m.Relationships()[0]), `"created_at":"2025-03-05T00:00:00Z"`)
+	assert.Contains(t, mustJSON(t, m), `"created_at":"2025-03-05T00:00:00Z"`)
+}
+
+func TestDryRun(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	d := NewDiagrammer(client, WithDryRun(), WithMaxBackgroundTokens(10))
+	result, err := d.Generate(context.Background(), "explain the revolution", strings.Repeat("taxes caused tension. ", 10))
+	require.NoError(t, err)
+
+	assert.Empty(t, client.calls)
+	assert.Empty(t, result.CausalChains)
+	require.NotNil(t, result.DryRun)
+	assert.False(t, result.DryRun.OK())
+	assert.Len(t, result.DryRun.Messages, 2)
+	assert.NotContains(t, result.DryRun.SystemPrompt, "{schema}")
+	require.Len(t, result.DryRun.Problems, 1)
+	assert.Contains(t, result.DryRun.Problems[0], "background knowledge")
+
+	result, err = NewDiagrammer(client, WithDryRun()).Generate(context.Background(), "explain the revolution", "taxes caused tension.")
+	require.NoError(t, err)
+	assert.Empty(t, client.calls)
+	assert.True(t, result.DryRun.OK())
+}
+
+func TestModelRefused(t *testing.T) {
+	client := &mockClient{contents: []string{""}, refusal: "I can't help with that."}
+
+	metrics := &fakeMetrics{failures: make(map[chat.FailureKind]int)}
+
+	_, err := NewDiagrammer(client, WithMetrics(metrics)).Generate(context.Background(), "explain the revolution", "taxes caused tension.")
+	var refused *openai.ErrModelRefused
+	require.ErrorAs(t, err, &refused)
+	assert.Equal(t, "I can't help with that.", refused.Refusal)
+	assert.Len(t, client.calls, 1)
+	assert.Equal(t, map[chat.FailureKind]int{chat.FailureRefusal: 1}, metrics.failures)
+}
+
+func TestDryRunTokenizer(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+	background := strings.Repeat("taxes caused tension. ", 10)
+
+	// the heuristic estimates ~55 tokens, within the budget
+	result, err := NewDiagrammer(client, WithDryRun(), WithMaxBackgroundTokens(100)).Generate(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun.OK())
+	assert.Equal(t, 55, result.DryRun.BackgroundTokens)
+
+	// a tokenizer counting every character as a token doesn't fit it
+	perChar := chat.TokenizerFunc(func(text string) int { return len(text) })
+	result, err = NewDiagrammer(client, WithDryRun(), WithMaxBackgroundTokens(100), WithTokenizer(perChar)).Generate(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+	assert.False(t, result.DryRun.OK())
+	assert.Equal(t, len(background), result.DryRun.BackgroundTokens)
+}
+
+func TestAppendAccumulatesBackground(t *testing.T) {
+	first := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+	})
+	second := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, first), mustJSON(t, second)}}
+
+	d := NewDiagrammer(client)
+	result, err := d.Append(context.Background(), "Taxes raised tensions, and tensions led to more taxes.")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"tax burden", "tensions", "tax burden"}}, result.Loops())
+
+	result, err = d.Append(context.Background(), "Tensions led to clashes, which raised tensions further.")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"clashes", "tensions", "clashes"},
+		{"tax burden", "tensions", "tax burden"},
+	}, result.Loops())
+
+	require.Len(t, client.calls, 2)
+	background := client.calls[1][0].Content
+	assert.Contains(t, background, "Taxes raised tensions")
+	assert.Contains(t, background, "Tensions led to clashes")
+}
+
+func TestPostProcessorsRunInOrder(t *testing.T) {
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	var order []string
+	dropClashes := func(m *Map) *Map {
+		order = append(order, "drop")
+		var rels []Relationship
+		for _, r := range m.Relationships() {
+			if r.From != "Clashes" && r.To != "Clashes" {
+				rels = append(rels, r)
+			}
+		}
+		return NewMap(rels)
+	}
+	retitle := func(m *Map) *Map {
+		order = append(order, "retitle")
+		m.Title = "Pruned"
+		return m
+	}
+
+	d := NewDiagrammer(client, WithPostProcessors(dropClashes, retitle))
+	result, err := d.Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"drop", "retitle"}, order)
+	assert.Equal(t, "Pruned", result.Title)
+	assert.Equal(t, NewSet("tax burden", "tensions", "resistance"), result.Variables())
+}
@@ -0,0 +1,200 @@
+package causal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type dotOptions struct {
+	title         bool
+	legend        bool
+	polaritySigns bool
+	layered       bool
+	// edges with a confidence below this are dashed
+	minConfidence float64
+}
+
+type DOTOption func(*dotOptions)
+
+// WithGraphTitle labels the rendered graph with the map's Title.
+func WithGraphTitle() DOTOption {
+	return func(opts *dotOptions) {
+		opts.title = true
+	}
+}
+
+// WithLegend adds a node explaining the link polarities and the R/B loop
+// labels.
+func WithLegend() DOTOption {
+	return func(opts *dotOptions) {
+		opts.legend = true
+	}
+}
+
+// WithPolaritySigns draws each link's polarity as a sign beside its
+// arrowhead, as is conventional in CLDs, rather than as a label midway
+// along it.
+func WithPolaritySigns() DOTOption {
+	return func(opts *dotOptions) {
+		opts.polaritySigns = true
+	}
+}
+
+// WithLayeredLayout lays the diagram out left to right in causal order
+// rather than with a force-directed layout: variables outside any loop
+// are ranked by how far downstream they are, and the variables of each
+// feedback loop are grouped into a cluster.  It reads much more easily
+// for mostly-acyclic maps.  It has no effect on maps with Positions.
+func WithLayeredLayout() DOTOption {
+	return func(opts *dotOptions) {
+		opts.layered = true
+	}
+}
+
+// WithLowConfidenceDashed dashes links the model gave a confidence below
+// threshold, as it does links tagged as assumptions.
+func WithLowConfidenceDashed(threshold float64) DOTOption {
+	return func(opts *dotOptions) {
+		opts.minConfidence = threshold
+	}
+}
+
+const dotLegend = `+ : change in the same direction\l- : change in the opposite direction\lR : reinforcing loop\lB : balancing loop\l`
+
+// DOT renders the map as a Graphviz digraph, one node per variable and one
+// edge, labeled with its polarity, per distinct link.  Links tagged as
+// assumptions are dashed.  Variables with Positions are pinned there.
+func (m *Map) DOT(opts ...DOTOption) string {
+	var options dotOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var b strings.Builder
+
+	positions := m.positions()
+	switch {
+	case len(positions) > 0:
+		// neato keeps pinned nodes where they are, like neato -n, and
+		// lays out any others around them
+		b.WriteString("digraph {\n\tlayout=neato\n\tnotranslate=true\n\toverlap=false\n\tsplines=true\n")
+	case options.layered:
+		// the layout attribute overrides the engine dot is run with
+		b.WriteString("digraph {\n\tlayout=dot\n\trankdir=LR\n")
+	default:
+		b.WriteString("digraph {\n\toverlap=false\n\tmode=KK\n")
+	}
+
+	if options.title && m.Title != "" {
+		fmt.Fprintf(&b, "\tlabel=%q\n\tlabelloc=t\n", m.Title)
+	}
+
+	names := m.displayNames()
+	for _, v := range m.Variables().Slice() {
+		attrs := fmt.Sprintf("label=%q", names[v])
+		if notes := m.VariableNotes(v); len(notes) > 0 {
+			attrs += fmt.Sprintf(", tooltip=%q", tooltip(notes))
+		}
+		if p, ok := positions[v]; ok {
+			// pos is in inches, and the trailing ! pins the node
+			attrs += fmt.Sprintf(", pos=\"%g,%g!\"", p.X/72, p.Y/72)
+		}
+		fmt.Fprintf(&b, "\t%q [%s]\n", v, attrs)
+	}
+
+	if options.layered && len(positions) == 0 {
+		m.writeLayers(&b)
+	}
+
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		attrs := fmt.Sprintf("label=%q", r.Polarity)
+		if options.polaritySigns {
+			attrs = fmt.Sprintf("headlabel=%q, labeldistance=1.5", r.Polarity)
+		}
+		if notes := m.EdgeNotes(from, to); len(notes) > 0 {
+			attrs += fmt.Sprintf(", tooltip=%q", tooltip(notes))
+		}
+		if r.Evidence == EvidenceAssumption || lowConfidence(r, options.minConfidence) {
+			attrs += ", style=dashed"
+		}
+		edge := fmt.Sprintf("\t%q -> %q [%s]\n", from, to, attrs)
+		if !seen.Contains(edge) {
+			seen.Add(edge)
+			b.WriteString(edge)
+		}
+	}
+
+	if options.legend {
+		fmt.Fprintf(&b, "\tlegend [shape=note, label=\"%s\"]\n", dotLegend)
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// writeLayers groups the variables of each feedback loop into a cluster,
+// and the remaining variables into ranks by their level in the condensed
+// graph.
+func (m *Map) writeLayers(b *strings.Builder) {
+	component, level := levels(m.Variables(), m.OutgoingEdges())
+
+	grouped := make(map[int][]string)
+	for _, v := range m.Variables().Slice() {
+		grouped[component[v]] = append(grouped[component[v]], v)
+	}
+
+	ranks := make(map[int][]string)
+	var clusters [][]string
+	for c, vars := range grouped {
+		if len(vars) > 1 {
+			clusters = append(clusters, vars)
+		} else {
+			ranks[level[c]] = append(ranks[level[c]], vars[0])
+		}
+	}
+	slices.SortFunc(clusters, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	for i, vars := range clusters {
+		fmt.Fprintf(b, "\tsubgraph cluster_loop%d {\n\t\tstyle=rounded\n\t\tcolor=gray\n", i+1)
+		for _, v := range vars {
+			fmt.Fprintf(b, "\t\t%q\n", v)
+		}
+		b.WriteString("\t}\n")
+	}
+
+	for _, l := range slices.Sorted(maps.Keys(ranks)) {
+		vars := ranks[l]
+		slices.Sort(vars)
+		b.WriteString("\t{ rank=same;")
+		for _, v := range vars {
+			fmt.Fprintf(b, " %q;", v)
+		}
+		b.WriteString(" }\n")
+	}
+}
+
+// WithLayoutHints returns a copy of m that renders with the given
+// variables fixed in place, as a user arranged them; the rest are laid
+// out around them.  Positions are keyed by variable name, matched
+// case-insensitively.
+func (m *Map) WithLayoutHints(positions map[string]Position) *Map {
+	hinted := *m
+	hinted.Positions = maps.Clone(positions)
+	return &hinted
+}
+
+// positions are the map's Positions keyed by normalized name.
+func (m *Map) positions() map[string]Position {
+	positions := make(map[string]Position, len(m.Positions))
+	for name, p := range m.Positions {
+		positions[normalizeVariable(name)] = p
+	}
+	return positions
+}
@@ -0,0 +1,122 @@
+package causal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDOTTitleAndLegend(t *testing.T) {
+	dot := testMap1.DOT()
+	assert.Contains(t, dot, `"tax burden" -> "tensions" [label="+"]`)
+	assert.NotContains(t, dot, testMap1.Title)
+	assert.NotContains(t, dot, "legend")
+
+	dot = testMap1.DOT(WithGraphTitle(), WithLegend())
+	assert.Contains(t, dot, `label="`+testMap1.Title+`"`)
+	assert.Contains(t, dot, "\tlegend [shape=note")
+	assert.Contains(t, dot, "R : reinforcing loop")
+}
+
+func TestDOTPolaritySigns(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Deaths", To: "Population", Polarity: "-"},
+		{From: "Population", To: "Deaths", Polarity: "+"},
+	})
+
+	dot := m.DOT(WithPolaritySigns())
+	assert.Contains(t, dot, `"deaths" -> "population" [headlabel="-", labeldistance=1.5]`)
+	assert.Contains(t, dot, `"population" -> "deaths" [headlabel="+", labeldistance=1.5]`)
+	assert.NotContains(t, dot, `[label="+"]`)
+	assert.NotContains(t, dot, `[label="-"]`)
+
+	assert.NotContains(t, m.DOT(), "headlabel")
+}
+
+func TestVisualSVGTimeout(t *testing.T) {
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz dot not installed")
+	}
+
+	var rels []Relationship
+	for i := range 200 {
+		for j := range 10 {
+			rels = append(rels, Relationship{From: fmt.Sprintf("v%d", i), To: fmt.Sprintf("v%d", (i*7+j*13)%200), Polarity: "+"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := NewMap(rels).VisualSVG(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "dot rendering aborted")
+}
+
+func TestSVGDataURI(t *testing.T) {
+	const prefix = "data:image/svg+xml;base64,"
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+
+	uri := svgDataURI(svg)
+	require.True(t, strings.HasPrefix(uri, prefix))
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+	require.NoError(t, err)
+	assert.Equal(t, svg, decoded)
+
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("graphviz dot not installed")
+	}
+	uri, err = testMap1.SVGDataURI(context.Background())
+	require.NoError(t, err)
+	decoded, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+	require.NoError(t, err)
+	assert.Contains(t, string(decoded), "<svg")
+}
+
+func TestDOTLayeredLayout(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Weather", To: "Crops", Polarity: "+"},
+		{From: "Rainfall", To: "Crops", Polarity: "+"},
+		{From: "Crops", To: "Food", Polarity: "+"},
+		{From: "Food", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Food", Polarity: "-"},
+		{From: "Population", To: "Pollution", Polarity: "+"},
+	})
+
+	dot := m.DOT(WithLayeredLayout())
+	assert.Contains(t, dot, "\tlayout=dot\n\trankdir=LR\n")
+	assert.NotContains(t, dot, "mode=KK")
+	assert.Contains(t, dot, `{ rank=same; "rainfall"; "weather"; }`)
+	assert.Contains(t, dot, `{ rank=same; "crops"; }`)
+	assert.Contains(t, dot, `{ rank=same; "pollution"; }`)
+	assert.Contains(t, dot, "\tsubgraph cluster_loop1 {\n\t\tstyle=rounded\n\t\tcolor=gray\n\t\t\"food\"\n\t\t\"population\"\n\t}\n")
+	assert.Equal(t, 3, strings.Count(dot, "rank=same"))
+
+	assert.NotContains(t, m.DOT(), "rank=same")
+}
+
+func TestDOTLayoutHints(t *testing.T) {
+	m := testMap1.WithLayoutHints(map[string]Position{
+		"Tax Burden": {X: 72, Y: 144},
+		"tensions":   {X: 36, Y: 0},
+	})
+	assert.Nil(t, testMap1.Positions)
+
+	dot := m.DOT(WithLayeredLayout())
+	assert.Contains(t, dot, "\tlayout=neato\n")
+	assert.NotContains(t, dot, "rank=same")
+	assert.Contains(t, dot, `"tax burden" [label="Tax Burden", pos="1,2!"]`)
+	assert.Contains(t, dot, `"tensions" [label="Tensions", pos="0.5,0!"]`)
+	assert.Contains(t, dot, `"clashes" [label="Clashes"]`)
+
+	assert.NotContains(t, testMap1.DOT(), "pos=")
+	assert.NotContains(t, testMap1.DOT(), "neato")
+}
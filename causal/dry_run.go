@@ -0,0 +1,49 @@
+package causal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+// DryRunReport describes the request Generate would have sent, and any
+// problems found with it.
+type DryRunReport struct {
+	SystemPrompt     string
+	Messages         []chat.Message
+	BackgroundTokens int
+	Problems         []string
+}
+
+func (r *DryRunReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// validate checks the request Generate is about to send, reporting the
+// problems it finds rather than failing on them.
+func (d diagrammer) validate(sysPrompt string, msgs []chat.Message, backgroundKnowledge string) *DryRunReport {
+	report := &DryRunReport{
+		SystemPrompt:     sysPrompt,
+		Messages:         msgs,
+		BackgroundTokens: d.tokenizer.CountTokens(backgroundKnowledge),
+	}
+
+	if strings.Contains(sysPrompt, "{schema}") {
+		report.Problems = append(report.Problems, "system prompt has an unrendered {schema} placeholder")
+	}
+	if d.maxBackgroundTokens > 0 && report.BackgroundTokens > d.maxBackgroundTokens {
+		report.Problems = append(report.Problems, fmt.Sprintf("background knowledge is ~%d tokens, more than the limit of %d", report.BackgroundTokens, d.maxBackgroundTokens))
+	}
+	if s := d.responseSchema; s == nil || s.Type != schema.Object || len(s.Properties) == 0 {
+		report.Problems = append(report.Problems, "response schema is not an object schema with properties")
+	}
+	for i, msg := range msgs {
+		if strings.TrimSpace(msg.Content) == "" {
+			report.Problems = append(report.Problems, fmt.Sprintf("message %d (%s) is empty", i, msg.Role))
+		}
+	}
+
+	return report
+}
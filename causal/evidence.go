@@ -0,0 +1,104 @@
+package causal
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Evidence distinguishes relationships grounded in the background
+// knowledge from those the model inferred on its own.
+type Evidence string
+
+const (
+	// EvidenceBacked relationships have reasoning that cites the
+	// background knowledge.
+	EvidenceBacked Evidence = "evidence"
+	// EvidenceAssumption relationships were inferred by the model, and
+	// need validating.
+	EvidenceAssumption Evidence = "assumption"
+)
+
+// minCitedWords is the number of consecutive words reasoning must share
+// with the background knowledge to count as citing it.  Shorter runs
+// ("an increase in the") are too common to mean anything.
+const minCitedWords = 5
+
+// words splits text into lowercase words, ignoring punctuation.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// cites reports whether text shares a run of minCitedWords words with the
+// background, whose runs are given.
+func cites(runs Set[string], text string) bool {
+	w := words(text)
+	for i := 0; i+minCitedWords <= len(w); i++ {
+		if runs.Contains(strings.Join(w[i:i+minCitedWords], " ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagEvidence marks each relationship as EvidenceBacked if its reasoning
+// or polarity reasoning quotes (a run of several words from) the
+// background knowledge, and EvidenceAssumption otherwise.  Maps generated
+// with background knowledge are tagged automatically.
+func (m *Map) TagEvidence(background string) {
+	runs := make(Set[string])
+	w := words(background)
+	for i := 0; i+minCitedWords <= len(w); i++ {
+		runs.Add(strings.Join(w[i:i+minCitedWords], " "))
+	}
+
+	for i := range m.CausalChains {
+		c := &m.CausalChains[i]
+		chainCites := cites(runs, c.Reasoning)
+		for j := range c.Relationships {
+			r := &c.Relationships[j]
+			if chainCites || cites(runs, r.PolarityReasoning) {
+				r.Evidence = EvidenceBacked
+			} else {
+				r.Evidence = EvidenceAssumption
+			}
+		}
+	}
+}
+
+// minGroundingWordLength is the length below which words in variable
+// names are too generic ("of", "and") to show a variable is grounded.
+const minGroundingWordLength = 3
+
+// UngroundedVariables returns the (normalized) variables none of whose
+// words appear in the background knowledge, sorted.  Words match when one
+// is a prefix of the other, so "Tax" is grounded by "taxes".  These are
+// variables the model may have invented.
+func (m *Map) UngroundedVariables(background string) []string {
+	backgroundWords := NewSet(words(background)...)
+
+	grounded := func(word string) bool {
+		for w := range backgroundWords {
+			if strings.HasPrefix(w, word) || (len(w) >= minGroundingWordLength && strings.HasPrefix(word, w)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var ungrounded []string
+	for _, v := range m.Variables().Slice() {
+		found := false
+		for _, word := range words(v) {
+			if len(word) >= minGroundingWordLength && grounded(word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ungrounded = append(ungrounded, v)
+		}
+	}
+	return ungrounded
+}
@@ -0,0 +1,44 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagEvidence(t *testing.T) {
+	background := "The Stamp Act sharply increased the tax burden on the colonies. As the tax burden grew, tensions with Britain rose."
+	m := NewMap([]Relationship{
+		{From: "Stamp Act", To: "Tax Burden", Polarity: "+", PolarityReasoning: "The Stamp Act sharply increased the tax burden."},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+", PolarityReasoning: "As the Tax Burden grew, tensions with Britain rose!"},
+		{From: "Tensions", To: "Weather", Polarity: "-", PolarityReasoning: "Tense colonists made the winters harsher."},
+	})
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+
+	result, err := NewDiagrammer(client).Generate(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+
+	evidence := make(map[string]Evidence)
+	for _, r := range result.Relationships() {
+		evidence[r.To] = r.Evidence
+	}
+	assert.Equal(t, map[string]Evidence{
+		"Tax Burden": EvidenceBacked,
+		"Tensions":   EvidenceBacked,
+		"Weather":    EvidenceAssumption,
+	}, evidence)
+
+	dot := result.DOT()
+	assert.Contains(t, dot, `"tensions" -> "weather" [label="-", style=dashed]`)
+	assert.Contains(t, dot, `"tax burden" -> "tensions" [label="+"]`)
+
+	// with no background, nothing is tagged
+	result, err = NewDiagrammer(client).Generate(context.Background(), "explain the revolution", "")
+	require.NoError(t, err)
+	for _, r := range result.Relationships() {
+		assert.Empty(t, r.Evidence)
+	}
+	assert.NotContains(t, result.DOT(), "dashed")
+}
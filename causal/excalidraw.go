@@ -0,0 +1,192 @@
+package causal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+const (
+	excalidrawBoxWidth  = 180
+	excalidrawBoxHeight = 60
+	excalidrawSpacing   = 120
+	excalidrawFontSize  = 16
+)
+
+type excalidrawBinding struct {
+	ElementID string  `json:"elementId"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+type excalidrawBound struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// excalidrawElement has the union of the fields used by the rectangles,
+// arrows and text labels we emit; Excalidraw fills in defaults for the
+// rest when importing.
+type excalidrawElement struct {
+	ID              string            `json:"id"`
+	Type            string            `json:"type"`
+	X               float64           `json:"x"`
+	Y               float64           `json:"y"`
+	Width           float64           `json:"width"`
+	Height          float64           `json:"height"`
+	Angle           float64           `json:"angle"`
+	StrokeColor     string            `json:"strokeColor"`
+	BackgroundColor string            `json:"backgroundColor"`
+	FillStyle       string            `json:"fillStyle"`
+	StrokeWidth     int               `json:"strokeWidth"`
+	StrokeStyle     string            `json:"strokeStyle"`
+	Roughness       int               `json:"roughness"`
+	Opacity         int               `json:"opacity"`
+	GroupIDs        []string          `json:"groupIds"`
+	Seed            int               `json:"seed"`
+	Version         int               `json:"version"`
+	IsDeleted       bool              `json:"isDeleted"`
+	Locked          bool              `json:"locked"`
+	BoundElements   []excalidrawBound `json:"boundElements"`
+
+	// rectangles
+	Roundness *struct {
+		Type int `json:"type"`
+	} `json:"roundness,omitempty"`
+
+	// arrows
+	Points       [][2]float64       `json:"points,omitempty"`
+	StartBinding *excalidrawBinding `json:"startBinding,omitempty"`
+	EndBinding   *excalidrawBinding `json:"endBinding,omitempty"`
+	EndArrowhead string             `json:"endArrowhead,omitempty"`
+
+	// text
+	Text          string `json:"text,omitempty"`
+	OriginalText  string `json:"originalText,omitempty"`
+	FontSize      int    `json:"fontSize,omitempty"`
+	FontFamily    int    `json:"fontFamily,omitempty"`
+	TextAlign     string `json:"textAlign,omitempty"`
+	VerticalAlign string `json:"verticalAlign,omitempty"`
+	ContainerID   string `json:"containerId,omitempty"`
+}
+
+type excalidrawScene struct {
+	Type     string              `json:"type"`
+	Version  int                 `json:"version"`
+	Source   string              `json:"source"`
+	Elements []excalidrawElement `json:"elements"`
+	AppState map[string]any      `json:"appState"`
+	Files    map[string]any      `json:"files"`
+}
+
+func newExcalidrawElement(id, typ string, seed int) excalidrawElement {
+	return excalidrawElement{
+		ID:              id,
+		Type:            typ,
+		StrokeColor:     "#1e1e1e",
+		BackgroundColor: "transparent",
+		FillStyle:       "solid",
+		StrokeWidth:     2,
+		StrokeStyle:     "solid",
+		Roughness:       1,
+		Opacity:         100,
+		GroupIDs:        []string{},
+		Seed:            seed,
+		Version:         1,
+		BoundElements:   []excalidrawBound{},
+	}
+}
+
+// excalidrawLabel returns a text element centered in container.
+func excalidrawLabel(container *excalidrawElement, text string, seed int) excalidrawElement {
+	label := newExcalidrawElement(container.ID+"-label", "text", seed)
+	label.Text = text
+	label.OriginalText = text
+	label.FontSize = excalidrawFontSize
+	label.FontFamily = 1
+	label.TextAlign = "center"
+	label.VerticalAlign = "middle"
+	label.ContainerID = container.ID
+	label.Width = float64(len(text) * excalidrawFontSize / 2)
+	label.Height = excalidrawFontSize * 1.25
+	label.X = container.X + container.Width/2 - label.Width/2
+	label.Y = container.Y + container.Height/2 - label.Height/2
+	container.BoundElements = append(container.BoundElements, excalidrawBound{ID: label.ID, Type: "text"})
+	return label
+}
+
+// Excalidraw renders the map as an Excalidraw scene, which tldraw can
+// import too, so the diagram can be edited by hand in a whiteboard tool.
+// Variables are laid out on a simple grid as labeled rectangles, and each
+// pair of connected variables gets one arrow labeled with its polarity.
+func (m *Map) Excalidraw() ([]byte, error) {
+	names := m.displayNames()
+	vars := m.Variables().Slice()
+	cols := int(math.Ceil(math.Sqrt(float64(len(vars)))))
+
+	seed := 0
+	nextSeed := func() int {
+		seed++
+		return seed
+	}
+
+	boxes := make(map[string]*excalidrawElement, len(vars))
+	for i, v := range vars {
+		box := newExcalidrawElement(fmt.Sprintf("var-%d", i), "rectangle", nextSeed())
+		box.X = float64((i % cols) * (excalidrawBoxWidth + excalidrawSpacing))
+		box.Y = float64((i / cols) * (excalidrawBoxHeight + excalidrawSpacing))
+		box.Width = excalidrawBoxWidth
+		box.Height = excalidrawBoxHeight
+		box.Roundness = &struct {
+			Type int `json:"type"`
+		}{Type: 3}
+		boxes[v] = &box
+	}
+
+	var arrows, labels []excalidrawElement
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if k := from + "\x00" + to; seen.Contains(k) {
+			continue
+		}
+		seen.Add(from + "\x00" + to)
+		src, dst := boxes[from], boxes[to]
+
+		arrow := newExcalidrawElement(fmt.Sprintf("edge-%d", len(arrows)), "arrow", nextSeed())
+		arrow.X = src.X + src.Width/2
+		arrow.Y = src.Y + src.Height/2
+		dx := dst.X + dst.Width/2 - arrow.X
+		dy := dst.Y + dst.Height/2 - arrow.Y
+		arrow.Width, arrow.Height = math.Abs(dx), math.Abs(dy)
+		arrow.Points = [][2]float64{{0, 0}, {dx, dy}}
+		arrow.StartBinding = &excalidrawBinding{ElementID: src.ID, Gap: 4}
+		arrow.EndBinding = &excalidrawBinding{ElementID: dst.ID, Gap: 4}
+		arrow.EndArrowhead = "arrow"
+		src.BoundElements = append(src.BoundElements, excalidrawBound{ID: arrow.ID, Type: "arrow"})
+		dst.BoundElements = append(dst.BoundElements, excalidrawBound{ID: arrow.ID, Type: "arrow"})
+
+		if r.Polarity != "" {
+			labels = append(labels, excalidrawLabel(&arrow, r.Polarity, nextSeed()))
+		}
+		arrows = append(arrows, arrow)
+	}
+
+	scene := excalidrawScene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "https://github.com/isee-systems/sd-ai",
+		Elements: []excalidrawElement{},
+		AppState: map[string]any{"viewBackgroundColor": "#ffffff"},
+		Files:    map[string]any{},
+	}
+	for _, v := range vars {
+		box := boxes[v]
+		label := excalidrawLabel(box, names[v], nextSeed())
+		scene.Elements = append(scene.Elements, *box, label)
+	}
+	scene.Elements = append(scene.Elements, arrows...)
+	scene.Elements = append(scene.Elements, labels...)
+
+	return json.MarshalIndent(scene, "", "  ")
+}
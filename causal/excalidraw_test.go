@@ -0,0 +1,51 @@
+package causal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcalidraw(t *testing.T) {
+	out, err := testMap1.Excalidraw()
+	require.NoError(t, err)
+
+	var scene struct {
+		Type     string `json:"type"`
+		Elements []struct {
+			ID          string `json:"id"`
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			ContainerID string `json:"containerId"`
+		} `json:"elements"`
+	}
+	require.NoError(t, json.Unmarshal(out, &scene))
+	assert.Equal(t, "excalidraw", scene.Type)
+
+	edges := 0
+	for _, tos := range testMap1.OutgoingEdges() {
+		edges += len(NewSet(tos...))
+	}
+
+	counts := make(map[string]int)
+	labels := make(map[string]string)
+	for _, e := range scene.Elements {
+		counts[e.Type]++
+		if e.ContainerID != "" {
+			labels[e.ContainerID] = e.Text
+		}
+	}
+	assert.Equal(t, testMap1.VariableCount(), counts["rectangle"])
+	assert.Equal(t, edges, counts["arrow"])
+
+	for _, e := range scene.Elements {
+		switch e.Type {
+		case "rectangle":
+			assert.NotEmpty(t, labels[e.ID], "variable %s has no label", e.ID)
+		case "arrow":
+			assert.Contains(t, []string{"+", "-"}, labels[e.ID], "edge %s has no polarity label", e.ID)
+		}
+	}
+}
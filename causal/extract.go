@@ -0,0 +1,256 @@
+package causal
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// cuePhrase is a phrase that signals a causal relationship between the
+// text before and after it.
+type cuePhrase struct {
+	phrase string
+	// backward cues name the effect first: "tension is due to taxes"
+	backward bool
+	negative bool
+}
+
+// cuePhrases are the phrases RuleBasedExtract looks for.  Longer phrases
+// come first, so "is caused by" wins over "caused".
+var cuePhrases = []cuePhrase{
+	{phrase: "is caused by", backward: true},
+	{phrase: "are caused by", backward: true},
+	{phrase: "was caused by", backward: true},
+	{phrase: "were caused by", backward: true},
+	{phrase: "results from", backward: true},
+	{phrase: "result from", backward: true},
+	{phrase: "because of", backward: true},
+	{phrase: "due to", backward: true},
+	{phrase: "results in"},
+	{phrase: "result in"},
+	{phrase: "resulted in"},
+	{phrase: "leads to"},
+	{phrase: "lead to"},
+	{phrase: "led to"},
+	{phrase: "causes"},
+	{phrase: "cause"},
+	{phrase: "caused"},
+	{phrase: "increases"},
+	{phrase: "increase"},
+	{phrase: "raises"},
+	{phrase: "raise"},
+	{phrase: "reduces", negative: true},
+	{phrase: "reduce", negative: true},
+	{phrase: "decreases", negative: true},
+	{phrase: "decrease", negative: true},
+	{phrase: "lowers", negative: true},
+	{phrase: "lower", negative: true},
+}
+
+// bare is whether the cue is a single word.  Many of those double as
+// nouns and adjectives ("an increase in", "the cause of", "lower
+// taxes"), so they are only tried after every multi-word cue.
+func (c cuePhrase) bare() bool {
+	return !strings.Contains(c.phrase, " ")
+}
+
+// quantityModifiers are words at the start of a variable's phrase that
+// say which way it changes, as in "fewer jobs" or "an increase in taxes";
+// a negative one flips the polarity of the relationship.
+var quantityModifiers = []struct {
+	word     string
+	negative bool
+}{
+	{word: "more "},
+	{word: "higher "},
+	{word: "greater "},
+	{word: "increased "},
+	{word: "rising "},
+	{word: "increase in "},
+	{word: "rise in "},
+	{word: "growth in "},
+	{word: "less ", negative: true},
+	{word: "fewer ", negative: true},
+	{word: "lower ", negative: true},
+	{word: "reduced ", negative: true},
+	{word: "decreased ", negative: true},
+	{word: "falling ", negative: true},
+	{word: "decrease in ", negative: true},
+	{word: "reduction in ", negative: true},
+	{word: "decline in ", negative: true},
+	{word: "fall in ", negative: true},
+}
+
+// RuleBasedExtract finds causal relationships in text without a model,
+// by looking for cue phrases like "leads to", "causes", "results in",
+// "due to", "increases" and "reduces" in each sentence.  The polarity
+// comes from the cue and from words like "more" and "fewer" before
+// either variable, so "less rain leads to more fires" is negative.  Each
+// relationship's reasoning is the sentence it was found in.  It is far
+// less capable than a model, but fast, free and deterministic, which
+// makes it a useful baseline.  It is an error for text to contain no
+// relationships at all.
+func RuleBasedExtract(text string) ([]Relationship, error) {
+	var rels []Relationship
+	for _, sentence := range strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune(".!?;\n", r)
+	}) {
+		if r, ok := extractRelationship(strings.TrimSpace(sentence)); ok {
+			rels = append(rels, r)
+		}
+	}
+	if len(rels) == 0 {
+		return nil, errors.New("no causal relationships found")
+	}
+	return rels, nil
+}
+
+type cueMatch struct {
+	cue   cuePhrase
+	start int
+}
+
+// extractRelationship finds the first cue phrase in the sentence with a
+// variable on either side of it, trying multi-word cues before bare ones.
+func extractRelationship(sentence string) (Relationship, bool) {
+	// pad with spaces so cues only match whole words.  The sentence isn't
+	// lowercased, as that can change its length, and so the offsets.
+	padded := " " + sentence + " "
+
+	var matches []cueMatch
+	for _, cue := range cuePhrases {
+		for offset := 0; ; {
+			i := indexFold(padded[offset:], " "+cue.phrase+" ")
+			if i < 0 {
+				break
+			}
+			matches = append(matches, cueMatch{cue: cue, start: offset + i})
+			offset += i + 1
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b cueMatch) int {
+		if a.cue.bare() != b.cue.bare() {
+			if a.cue.bare() {
+				return 1
+			}
+			return -1
+		}
+		return a.start - b.start
+	})
+
+	for _, match := range matches {
+		// padded has a leading space, so its index into sentence is the
+		// same as the match's start
+		before := sentence[:match.start]
+		after := sentence[min(match.start+len(match.cue.phrase), len(sentence)):]
+		if match.cue.bare() && nounFollower(after) {
+			continue
+		}
+
+		cause, effect := before, after
+		if match.cue.backward {
+			cause, effect = after, before
+		}
+		from, fromNegative := variablePhrase(cause, match.cue.backward)
+		to, toNegative := variablePhrase(effect, !match.cue.backward)
+		if from == "" || to == "" || strings.EqualFold(from, to) {
+			continue
+		}
+
+		polarity := "+"
+		if match.cue.negative != fromNegative != toNegative {
+			polarity = "-"
+		}
+		return Relationship{
+			From:              from,
+			To:                to,
+			Polarity:          polarity,
+			Reasoning:         sentence,
+			PolarityReasoning: "The text says " + from + " " + match.cue.phrase + " " + to + ".",
+		}, true
+	}
+	return Relationship{}, false
+}
+
+// variablePhrase extracts a variable's name from the text on one side of
+// a cue, and whether it is qualified as decreasing.  Text after a cue
+// (leading) runs up to the first clause break; text before a cue is
+// taken from the last one.
+func variablePhrase(text string, leading bool) (string, bool) {
+	breaks := []string{",", " and ", " which ", " because ", " but "}
+	if leading {
+		for _, b := range breaks {
+			if i := indexFold(text, b); i >= 0 {
+				text = text[:i]
+			}
+		}
+	} else {
+		for _, b := range breaks {
+			if i := lastIndexFold(text, b); i >= 0 {
+				text = text[i+len(b):]
+			}
+		}
+	}
+
+	// "police are due to cuts" names the variable "police"
+	name := strings.TrimSpace(text)
+	for _, copula := range []string{" is", " are", " was", " were"} {
+		if len(name) > len(copula) && strings.EqualFold(name[len(name)-len(copula):], copula) {
+			name = name[:len(name)-len(copula)]
+			break
+		}
+	}
+
+	name = trimPrefixFold(name, articles)
+	if isArticle(name) {
+		// "An increase ..." leaves just the article before the cue
+		return "", false
+	}
+	negative := false
+	for _, m := range quantityModifiers {
+		if trimmed := trimPrefixFold(name, []string{m.word}); trimmed != name {
+			name, negative = trimmed, m.negative
+			break
+		}
+	}
+	return strings.Trim(tidyName(name), ` "'()`), negative
+}
+
+// nounFollower reports whether the text after a bare cue shows it was
+// used as a noun, as in "an increase in taxes" or "the cause of riots".
+func nounFollower(after string) bool {
+	after = strings.ToLower(strings.TrimSpace(after))
+	return strings.HasPrefix(after, "in ") || strings.HasPrefix(after, "of ")
+}
+
+func isArticle(word string) bool {
+	for _, article := range articles {
+		if strings.EqualFold(word, strings.TrimSpace(article)) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexFold is strings.Index, ignoring case.  substr must be ASCII, so a
+// match is always the same length as substr and starts and ends on rune
+// boundaries.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastIndexFold is strings.LastIndex, ignoring case, with the same
+// requirements as indexFold.
+func lastIndexFold(s, substr string) int {
+	for i := len(s) - len(substr); i >= 0; i-- {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
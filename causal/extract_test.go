@@ -0,0 +1,74 @@
+package causal
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleBasedExtract(t *testing.T) {
+	tests := []struct {
+		text               string
+		from, to, polarity string
+	}{
+		{"Higher taxes lead to tension.", "taxes", "tension", "+"},
+		{"Taxation leads to resentment.", "Taxation", "resentment", "+"},
+		{"The boycott led to shortages.", "boycott", "shortages", "+"},
+		{"Stress causes aggressive driving.", "Stress", "aggressive driving", "+"},
+		{"Congestion results in delays.", "Congestion", "delays", "+"},
+		{"Anger is caused by delays.", "delays", "Anger", "+"},
+		{"Accidents are due to distracted driving.", "distracted driving", "Accidents", "+"},
+		{"Fatigue increases irritability.", "Fatigue", "irritability", "+"},
+		{"Sleep reduces irritability.", "Sleep", "irritability", "-"},
+		{"Exercise decreases stress.", "Exercise", "stress", "-"},
+		{"Less sleep leads to more accidents.", "sleep", "accidents", "-"},
+		{"Lower taxes lead to fewer protests.", "taxes", "protests", "+"},
+		{"In the colonies, the Stamp Act caused protests, which grew.", "Stamp Act", "protests", "+"},
+		{"Fewer police are due to budget cuts.", "budget cuts", "police", "-"},
+		{"An increase in taxes leads to protests.", "taxes", "protests", "+"},
+		{"A decrease in rainfall causes crop failures.", "rainfall", "crop failures", "-"},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			rels, err := RuleBasedExtract(test.text)
+			require.NoError(t, err)
+			require.Len(t, rels, 1)
+			assert.Equal(t, test.from, rels[0].From)
+			assert.Equal(t, test.to, rels[0].To)
+			assert.Equal(t, test.polarity, rels[0].Polarity)
+			assert.Equal(t, test.text[:len(test.text)-1], rels[0].Reasoning)
+		})
+	}
+}
+
+func TestRuleBasedExtractText(t *testing.T) {
+	rels, err := RuleBasedExtract("Taxes lead to tension. The weather was fine!  Tension causes clashes; clashes increase tension")
+	require.NoError(t, err)
+	require.Len(t, rels, 3)
+	assert.Equal(t, Relationship{From: "Taxes", To: "tension", Polarity: "+", Reasoning: "Taxes lead to tension", PolarityReasoning: "The text says Taxes lead to tension."}, rels[0])
+	assert.Equal(t, [2]string{"Tension", "clashes"}, [2]string{rels[1].From, rels[1].To})
+	assert.Equal(t, [2]string{"clashes", "tension"}, [2]string{rels[2].From, rels[2].To})
+
+	_, err = RuleBasedExtract("The weather was fine.")
+	assert.Error(t, err)
+
+	// offsets aren't thrown off by text whose length changes when
+	// lowercased
+	rels, err = RuleBasedExtract(strings.Repeat("Ⱥ", 40) + " causes x")
+	require.NoError(t, err)
+	assert.Equal(t, [2]string{strings.Repeat("Ⱥ", 40), "x"}, [2]string{rels[0].From, rels[0].To})
+	rels, err = RuleBasedExtract("İstanbul traffic LEADS TO delays")
+	require.NoError(t, err)
+	assert.Equal(t, [2]string{"İstanbul traffic", "delays"}, [2]string{rels[0].From, rels[0].To})
+	assert.True(t, utf8.ValidString(rels[0].From))
+	rels, err = RuleBasedExtract("ȺȺȺ, and İİİ taxes LEAD TO ȺȺȺ protests, Which spread")
+	require.NoError(t, err)
+	assert.Equal(t, [2]string{"İİİ taxes", "ȺȺȺ protests"}, [2]string{rels[0].From, rels[0].To})
+
+	// bare cues used as nouns aren't relationships
+	_, err = RuleBasedExtract("The cause of the riots is unclear.  An increase in rainfall.")
+	assert.Error(t, err)
+}
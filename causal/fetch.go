@@ -0,0 +1,128 @@
+package causal
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxFetchBytes caps the size of a page FetchText will read.
+const MaxFetchBytes = 2 << 20
+
+// blockTags are the HTML elements that start a new line of text.
+var blockTags = NewSet(
+	"address", "article", "aside", "blockquote", "br", "dd", "div", "dl",
+	"dt", "figcaption", "footer", "h1", "h2", "h3", "h4", "h5", "h6",
+	"header", "hr", "li", "main", "nav", "ol", "p", "pre", "section",
+	"table", "td", "th", "tr", "ul",
+)
+
+// FetchText retrieves the page at url and returns its readable text:
+// HTML is stripped of its markup, scripts and styles, and plain text is
+// returned as is.  Other content types, and pages larger than
+// MaxFetchBytes, are an error.
+func FetchText(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("http.NewRequest: %w", err)
+	}
+	req.Header.Set("Accept", "text/html, text/plain")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http.DefaultClient.Do: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("http status code: %d", resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("mime.ParseMediaType: %w", err)
+	}
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxFetchBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("io.ReadAll: %w", err)
+	}
+	if len(body) > MaxFetchBytes {
+		return "", fmt.Errorf("page is larger than %d bytes", MaxFetchBytes)
+	}
+
+	if mediaType == "text/plain" {
+		return string(body), nil
+	}
+	return htmlText(string(body)), nil
+}
+
+// htmlText extracts the readable text from an HTML document.  It is a
+// deliberately simple scanner rather than a parser: good enough for
+// articles, which is all it's for.
+func htmlText(doc string) string {
+	var b strings.Builder
+	skipUntil := ""
+	for len(doc) > 0 {
+		start := strings.IndexByte(doc, '<')
+		if start < 0 {
+			if skipUntil == "" {
+				b.WriteString(html.UnescapeString(doc))
+			}
+			break
+		}
+		if skipUntil == "" {
+			b.WriteString(html.UnescapeString(doc[:start]))
+		}
+		doc = doc[start:]
+
+		if strings.HasPrefix(doc, "<!--") {
+			end := strings.Index(doc, "-->")
+			if end < 0 {
+				break
+			}
+			doc = doc[end+len("-->"):]
+			continue
+		}
+
+		end := strings.IndexByte(doc, '>')
+		if end < 0 {
+			break
+		}
+		tag := doc[1:end]
+		doc = doc[end+1:]
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimPrefix(tag, "/"))
+		if i := strings.IndexAny(name, " \t\n/"); i >= 0 {
+			name = name[:i]
+		}
+
+		switch {
+		case skipUntil != "":
+			if closing && name == skipUntil {
+				skipUntil = ""
+			}
+		case !closing && (name == "script" || name == "style" || name == "head"):
+			skipUntil = name
+		case blockTags.Contains(name):
+			b.WriteByte('\n')
+		}
+	}
+
+	// collapse runs of whitespace, keeping paragraphs on their own lines
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
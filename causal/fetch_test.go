@@ -0,0 +1,71 @@
+package causal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html>
+<head><title>The Road to Revolution</title><style>p { color: red; }</style></head>
+<body>
+  <!-- navigation -->
+  <script>var tracking = "increase";</script>
+  <h1>The Road to Revolution</h1>
+  <p>The Stamp Act increased the <b>tax burden</b> on the colonies.</p>
+  <p>Higher taxes led to protests &amp; rising tensions.</p>
+</body>
+</html>`
+
+func newArticleServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(articleHTML))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG"))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(strings.Repeat("taxes ", MaxFetchBytes/5)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchText(t *testing.T) {
+	srv := newArticleServer(t)
+
+	text, err := FetchText(context.Background(), srv.URL+"/article")
+	require.NoError(t, err)
+	assert.Equal(t, "The Road to Revolution\nThe Stamp Act increased the tax burden on the colonies.\nHigher taxes led to protests & rising tensions.", text)
+
+	_, err = FetchText(context.Background(), srv.URL+"/image")
+	assert.ErrorContains(t, err, `unsupported content type "image/png"`)
+	_, err = FetchText(context.Background(), srv.URL+"/huge")
+	assert.ErrorContains(t, err, "larger than")
+	_, err = FetchText(context.Background(), srv.URL+"/missing")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestGenerateFromURL(t *testing.T) {
+	srv := newArticleServer(t)
+	client := &mockClient{contents: []string{mustJSON(t, testMap1)}}
+
+	_, err := NewDiagrammer(client).GenerateFromURL(context.Background(), "explain the revolution", srv.URL+"/article")
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 1)
+	assert.Contains(t, client.calls[0][0].Content, "Higher taxes led to protests & rising tensions.")
+	assert.NotContains(t, client.calls[0][0].Content, "tracking")
+}
@@ -0,0 +1,123 @@
+package causal
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+)
+
+type frontendLink struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Polarity string `json:"polarity"`
+}
+
+type frontendMap struct {
+	Variables []string       `json:"variables"`
+	Links     []frontendLink `json:"links"`
+}
+
+// displayNames maps each normalized variable name to the first spelling of
+// it that appears in the map.
+func (m *Map) displayNames() map[string]string {
+	names := make(map[string]string)
+	add := func(name string) {
+		if k := normalizeVariable(name); names[k] == "" {
+			names[k] = name
+		}
+	}
+	for _, chain := range m.CausalChains {
+		add(chain.InitialVariable)
+		for _, r := range chain.Relationships {
+			add(r.Variable)
+		}
+	}
+	return names
+}
+
+// ToFrontendJSON flattens the map into the {variables, links} shape the
+// SD-AI frontend consumes.  Variables and links are sorted, and duplicate
+// links removed, so the output is stable.
+func (m *Map) ToFrontendJSON() ([]byte, error) {
+	names := m.displayNames()
+
+	fm := frontendMap{
+		Variables: make([]string, 0, len(names)),
+		Links:     []frontendLink{},
+	}
+	for _, v := range m.Variables().Slice() {
+		fm.Variables = append(fm.Variables, names[v])
+	}
+
+	for _, r := range m.Relationships() {
+		fm.Links = append(fm.Links, frontendLink{
+			From:     names[normalizeVariable(r.From)],
+			To:       names[normalizeVariable(r.To)],
+			Polarity: r.Polarity,
+		})
+	}
+	slices.SortFunc(fm.Links, func(a, b frontendLink) int {
+		return cmp.Or(
+			cmp.Compare(normalizeVariable(a.From), normalizeVariable(b.From)),
+			cmp.Compare(normalizeVariable(a.To), normalizeVariable(b.To)),
+			cmp.Compare(a.Polarity, b.Polarity),
+		)
+	})
+	fm.Links = slices.Compact(fm.Links)
+
+	return json.MarshalIndent(fm, "", "  ")
+}
+
+type nodeLinkNode struct {
+	ID string `json:"id"`
+}
+
+type nodeLinkLink struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Polarity string `json:"polarity"`
+}
+
+type nodeLinkGraph struct {
+	Directed   bool              `json:"directed"`
+	Multigraph bool              `json:"multigraph"`
+	Graph      map[string]string `json:"graph"`
+	Nodes      []nodeLinkNode    `json:"nodes"`
+	Links      []nodeLinkLink    `json:"links"`
+}
+
+// NodeLinkJSON renders the map in the node-link format NetworkX reads with
+// json_graph.node_link_graph.  As that is not a multigraph, only the first
+// edge between each pair of variables is included.
+func (m *Map) NodeLinkJSON() ([]byte, error) {
+	names := m.displayNames()
+
+	g := nodeLinkGraph{
+		Directed: true,
+		Graph:    map[string]string{},
+		Nodes:    []nodeLinkNode{},
+		Links:    []nodeLinkLink{},
+	}
+	if m.Title != "" {
+		g.Graph["name"] = m.Title
+	}
+
+	for _, v := range m.Variables().Slice() {
+		g.Nodes = append(g.Nodes, nodeLinkNode{ID: names[v]})
+	}
+
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if k := from + "\x00" + to; !seen.Contains(k) {
+			seen.Add(k)
+			g.Links = append(g.Links, nodeLinkLink{
+				Source:   names[from],
+				Target:   names[to],
+				Polarity: r.Polarity,
+			})
+		}
+	}
+
+	return json.MarshalIndent(g, "", "  ")
+}
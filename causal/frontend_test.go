@@ -0,0 +1,47 @@
+package causal
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToFrontendJSON(t *testing.T) {
+	m, err := ParseMap([]byte(roadRage1))
+	require.NoError(t, err)
+
+	out, err := m.ToFrontendJSON()
+	require.NoError(t, err)
+
+	golden, err := os.ReadFile("testdata/road_rage_frontend.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(golden), string(out))
+}
+
+func TestNodeLinkJSON(t *testing.T) {
+	out, err := testMap1.NodeLinkJSON()
+	require.NoError(t, err)
+
+	var g struct {
+		Directed bool              `json:"directed"`
+		Graph    map[string]string `json:"graph"`
+		Nodes    []struct {
+			ID string `json:"id"`
+		} `json:"nodes"`
+		Links []struct {
+			Source   string `json:"source"`
+			Target   string `json:"target"`
+			Polarity string `json:"polarity"`
+		} `json:"links"`
+	}
+	require.NoError(t, json.Unmarshal(out, &g))
+
+	assert.True(t, g.Directed)
+	assert.Equal(t, testMap1.Title, g.Graph["name"])
+	assert.Len(t, g.Nodes, testMap1.VariableCount())
+	assert.Len(t, g.Links, testMap1.EdgeCount())
+	assert.Equal(t, "Clashes", g.Nodes[0].ID)
+}
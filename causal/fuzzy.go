@@ -0,0 +1,65 @@
+package causal
+
+// minSimilarity is how close (1 is identical) a query must be to a
+// variable name for a fuzzy match to be accepted.
+const minSimilarity = 0.7
+
+// levenshtein is the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// similarity scales the edit distance between a and b into [0, 1].
+func similarity(a, b string) float64 {
+	n := max(len([]rune(a)), len([]rune(b)))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(n)
+}
+
+// closestVariable returns the candidate most similar to query, if any is
+// at least minSimilarity.  Ties go to the alphabetically-first candidate.
+func closestVariable(query string, candidates []string) (string, bool) {
+	var best string
+	bestScore := -1.0
+	for _, c := range candidates {
+		if score := similarity(query, c); score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best, bestScore >= minSimilarity
+}
+
+// FindVariable resolves a user-typed variable name to the normalized name
+// used by the map.  Matching is case-insensitive, and falls back to the
+// closest variable by edit distance when there is no exact match.
+func (m *Map) FindVariable(query string) (canonical string, ok bool) {
+	query = normalizeVariable(query)
+	vars := m.Variables()
+	if vars.Contains(query) {
+		return query, true
+	}
+
+	return closestVariable(query, vars.Slice())
+}
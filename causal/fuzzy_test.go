@@ -0,0 +1,30 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("clashes", "clashes"))
+	assert.Equal(t, 1, levenshtein("clashes", "clashe"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 4, levenshtein("", "four"))
+}
+
+func TestFindVariable(t *testing.T) {
+	for query, expected := range map[string]string{
+		"Clashes":    "clashes",
+		"clash":      "clashes",
+		"taxburden":  "tax burden",
+		" TENSIONS ": "tensions",
+	} {
+		v, ok := testMap1.FindVariable(query)
+		assert.True(t, ok, query)
+		assert.Equal(t, expected, v, query)
+	}
+
+	_, ok := testMap1.FindVariable("population")
+	assert.False(t, ok)
+}
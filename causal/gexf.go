@@ -0,0 +1,105 @@
+package causal
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID    string `xml:"id,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfDocument struct {
+	XMLName        xml.Name `xml:"gexf"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Version        string   `xml:"version,attr"`
+	Meta           struct {
+		Creator     string `xml:"creator"`
+		Description string `xml:"description,omitempty"`
+	} `xml:"meta"`
+	Graph struct {
+		DefaultEdgeType string `xml:"defaultedgetype,attr"`
+		Mode            string `xml:"mode,attr"`
+		Attributes      struct {
+			Class     string          `xml:"class,attr"`
+			Mode      string          `xml:"mode,attr"`
+			Attribute []gexfAttribute `xml:"attribute"`
+		} `xml:"attributes"`
+		Nodes []gexfNode `xml:"nodes>node"`
+		Edges []gexfEdge `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// gexfPolarity is the id of the edge attribute holding the polarity.
+const gexfPolarity = "polarity"
+
+// GEXF renders the map in GEXF 1.3, Gephi's native format: a node per
+// variable and a directed edge, with a polarity attribute (and label),
+// for the first link between each pair of variables.  The map's title,
+// if any, becomes the description.
+func (m *Map) GEXF() ([]byte, error) {
+	var doc gexfDocument
+	doc.Xmlns = "http://gexf.net/1.3"
+	doc.XmlnsXSI = "http://www.w3.org/2001/XMLSchema-instance"
+	doc.SchemaLocation = "http://gexf.net/1.3 http://gexf.net/1.3/gexf.xsd"
+	doc.Version = "1.3"
+	doc.Meta.Creator = "sd-ai"
+	doc.Meta.Description = m.Title
+
+	g := &doc.Graph
+	g.DefaultEdgeType = "directed"
+	g.Mode = "static"
+	g.Attributes.Class = "edge"
+	g.Attributes.Mode = "static"
+	g.Attributes.Attribute = []gexfAttribute{{ID: gexfPolarity, Title: "polarity", Type: "string"}}
+
+	names := m.displayNames()
+	ids := make(map[string]string)
+	for i, v := range m.Variables().Slice() {
+		ids[v] = fmt.Sprintf("n%d", i)
+		g.Nodes = append(g.Nodes, gexfNode{ID: ids[v], Label: names[v]})
+	}
+
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if k := from + "\x00" + to; !seen.Contains(k) {
+			seen.Add(k)
+			g.Edges = append(g.Edges, gexfEdge{
+				ID:        fmt.Sprintf("e%d", len(g.Edges)),
+				Source:    ids[from],
+				Target:    ids[to],
+				Label:     r.Polarity,
+				AttValues: []gexfAttValue{{For: gexfPolarity, Value: r.Polarity}},
+			})
+		}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("xml.MarshalIndent: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
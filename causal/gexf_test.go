@@ -0,0 +1,51 @@
+package causal
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGEXF(t *testing.T) {
+	out, err := testMap1.GEXF()
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `<gexf xmlns="http://gexf.net/1.3"`)
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Graph   struct {
+			DefaultEdgeType string `xml:"defaultedgetype,attr"`
+			Nodes           []struct {
+				ID    string `xml:"id,attr"`
+				Label string `xml:"label,attr"`
+			} `xml:"nodes>node"`
+			Edges []struct {
+				Source    string `xml:"source,attr"`
+				Target    string `xml:"target,attr"`
+				AttValues []struct {
+					For   string `xml:"for,attr"`
+					Value string `xml:"value,attr"`
+				} `xml:"attvalues>attvalue"`
+			} `xml:"edges>edge"`
+		} `xml:"graph"`
+	}
+	require.NoError(t, xml.Unmarshal(out, &doc))
+
+	assert.Equal(t, "1.3", doc.Version)
+	assert.Equal(t, "directed", doc.Graph.DefaultEdgeType)
+	assert.Len(t, doc.Graph.Nodes, testMap1.VariableCount())
+	assert.Len(t, doc.Graph.Edges, 7)
+
+	ids := make(map[string]bool)
+	for _, n := range doc.Graph.Nodes {
+		ids[n.ID] = true
+	}
+	for _, e := range doc.Graph.Edges {
+		assert.True(t, ids[e.Source] && ids[e.Target])
+		require.Len(t, e.AttValues, 1)
+		assert.Equal(t, gexfPolarity, e.AttValues[0].For)
+		assert.Equal(t, "+", e.AttValues[0].Value)
+	}
+}
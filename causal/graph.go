@@ -0,0 +1,591 @@
+package causal
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// reachable returns the variables reachable from start by following
+// directed edges, not including start itself unless it is on a cycle.
+func reachable(outgoing map[string][]string, start string) Set[string] {
+	seen := make(Set[string])
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, next := range outgoing[v] {
+			if !seen.Contains(next) {
+				seen.Add(next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// Influences returns the variables v directly influences, sorted.
+// Names are normalized, and v is matched case-insensitively.
+func (m *Map) Influences(v string) []string {
+	return NewSet(m.OutgoingEdges()[normalizeVariable(v)]...).Slice()
+}
+
+// InfluencedBy returns the variables that directly influence v, sorted.
+// Names are normalized, and v is matched case-insensitively.
+func (m *Map) InfluencedBy(v string) []string {
+	v = normalizeVariable(v)
+	predecessors := make(Set[string])
+	for from, tos := range m.OutgoingEdges() {
+		if slices.Contains(tos, v) {
+			predecessors.Add(from)
+		}
+	}
+	return predecessors.Slice()
+}
+
+// Acyclic reports whether the map has no feedback loops at all, i.e. it
+// is an open causal chain (or several).  Unlike checking Loops, this is
+// exact even for maps too large for an exhaustive loop search.
+func (m *Map) Acyclic() bool {
+	outgoing := m.OutgoingEdges()
+
+	// Kahn's algorithm: repeatedly remove variables with no incoming
+	// edges; anything left over is on a cycle
+	inDegree := make(map[string]int)
+	for v := range m.Variables() {
+		inDegree[v] = 0
+	}
+	for _, tos := range outgoing {
+		for _, to := range tos {
+			inDegree[to]++
+		}
+	}
+
+	var queue []string
+	for v, n := range inDegree {
+		if n == 0 {
+			queue = append(queue, v)
+		}
+	}
+
+	removed := 0
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		removed++
+		for _, to := range outgoing[v] {
+			inDegree[to]--
+			if inDegree[to] == 0 {
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	return removed == len(inDegree)
+}
+
+// components partitions the variables into strongly connected components
+// (using Tarjan's algorithm), returning the component index of each.
+// Two variables are in the same component exactly when each is reachable
+// from the other, i.e. they share a feedback loop.
+func components(vars Set[string], outgoing map[string][]string) map[string]int {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(Set[string])
+	component := make(map[string]int)
+	var stack []string
+	var nComponents int
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack.Add(v)
+
+		for _, w := range outgoing[v] {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack.Contains(w) {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				delete(onStack, w)
+				component[w] = nComponents
+				if w == v {
+					break
+				}
+			}
+			nComponents++
+		}
+	}
+
+	for _, v := range vars.Slice() {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+	return component
+}
+
+// levels assigns each strongly connected component a level in the
+// condensed (acyclic) graph: the length of the longest path to it from a
+// component without incoming links.  It returns the component of each
+// variable, as from components, and the level of each component.
+func levels(vars Set[string], outgoing map[string][]string) (component map[string]int, level map[int]int) {
+	component = components(vars, outgoing)
+
+	successors := make(map[int][]int)
+	for from, tos := range outgoing {
+		for _, to := range tos {
+			if component[from] != component[to] {
+				successors[component[from]] = append(successors[component[from]], component[to])
+			}
+		}
+	}
+
+	// Tarjan's algorithm numbers components in reverse topological
+	// order, so counting down visits each after all its predecessors
+	n := 0
+	for _, c := range component {
+		n = max(n, c+1)
+	}
+	level = make(map[int]int)
+	for c := n - 1; c >= 0; c-- {
+		for _, next := range successors[c] {
+			level[next] = max(level[next], level[c]+1)
+		}
+	}
+	return component, level
+}
+
+// StronglyConnectedComponents partitions the variables into groups in
+// which every variable is reachable from every other.  A component with
+// more than one variable is a region of coupled feedback: a tightly
+// coupled subsystem.  Each component is sorted, and components are
+// ordered largest first, then by their first variable.
+func (m *Map) StronglyConnectedComponents() [][]string {
+	component := components(m.Variables(), m.OutgoingEdges())
+
+	grouped := make(map[int][]string)
+	for _, v := range m.Variables().Slice() {
+		grouped[component[v]] = append(grouped[component[v]], v)
+	}
+
+	sccs := make([][]string, 0, len(grouped))
+	for _, vars := range grouped {
+		sccs = append(sccs, vars)
+	}
+	slices.SortFunc(sccs, func(a, b []string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a[0], b[0]))
+	})
+	return sccs
+}
+
+// LoopsOnly returns the core feedback structure of the map: just the
+// variables and edges that are part of at least one feedback loop.
+func (m *Map) LoopsOnly() *Map {
+	component := components(m.Variables(), m.OutgoingEdges())
+
+	var rels []Relationship
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if component[from] == component[to] {
+			rels = append(rels, r)
+		}
+	}
+
+	core := NewMap(rels)
+	core.Title = m.Title
+	core.Explanation = m.Explanation
+	core.Annotations = carryAnnotations(core, []*Map{m}, normalizeVariable)
+	return core
+}
+
+// CouplingEdges returns the links that transmit influence between
+// subsystems: those from one feedback loop (strongly connected component)
+// to another, or between a loop and a variable outside any loop.  Links
+// within a loop, or between variables that aren't in any loop, are
+// excluded.  For each pair of variables, the first relationship between
+// them is returned.
+func (m *Map) CouplingEdges() []Relationship {
+	outgoing := m.OutgoingEdges()
+	component := components(m.Variables(), outgoing)
+
+	sizes := make(map[int]int)
+	for _, c := range component {
+		sizes[c]++
+	}
+	inLoop := func(v string) bool {
+		return sizes[component[v]] > 1
+	}
+
+	var coupling []Relationship
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if component[from] == component[to] || (!inLoop(from) && !inLoop(to)) {
+			continue
+		}
+		if k := from + "\x00" + to; !seen.Contains(k) {
+			seen.Add(k)
+			coupling = append(coupling, r)
+		}
+	}
+	return coupling
+}
+
+// InfluenceScore ranks each variable by its downstream reach: the fraction
+// of the other variables in the map it influences, directly or
+// indirectly.  Variables with high scores are systemic drivers.
+func (m *Map) InfluenceScore() map[string]float64 {
+	vars := m.Variables()
+	outgoing := m.OutgoingEdges()
+
+	scores := make(map[string]float64, len(vars))
+	if len(vars) < 2 {
+		for v := range vars {
+			scores[v] = 0
+		}
+		return scores
+	}
+
+	for v := range vars {
+		reach := reachable(outgoing, v)
+		n := len(reach)
+		if reach.Contains(v) {
+			n--
+		}
+		scores[v] = float64(n) / float64(len(vars)-1)
+	}
+	return scores
+}
+
+// distances returns the length, in links, of the shortest path from start
+// to each variable reachable from it (other than start itself).
+func distances(outgoing map[string][]string, start string) map[string]int {
+	dist := map[string]int{start: 0}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, next := range outgoing[v] {
+			if _, ok := dist[next]; !ok {
+				dist[next] = dist[v] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	delete(dist, start)
+	return dist
+}
+
+// weakComponentOf labels each variable with its weakly connected
+// component: variables are in the same one when they are linked, ignoring
+// the direction of the links.
+func weakComponentOf(vars Set[string], outgoing map[string][]string) map[string]int {
+	neighbors := make(map[string][]string)
+	for from, tos := range outgoing {
+		for _, to := range tos {
+			neighbors[from] = append(neighbors[from], to)
+			neighbors[to] = append(neighbors[to], from)
+		}
+	}
+
+	component := make(map[string]int)
+	n := 0
+	for _, v := range vars.Slice() {
+		if _, ok := component[v]; ok {
+			continue
+		}
+		component[v] = n
+		for w := range reachable(neighbors, v) {
+			component[w] = n
+		}
+		n++
+	}
+	return component
+}
+
+// Diameter is the longest shortest path, in links, between any two
+// variables of the map's largest (weakly) connected component, following
+// the links' direction.  It is 0 for maps without links.
+func (m *Map) Diameter() int {
+	vars := m.Variables()
+	outgoing := m.OutgoingEdges()
+	component := weakComponentOf(vars, outgoing)
+
+	sizes := make(map[int]int)
+	largest := 0
+	for _, v := range vars.Slice() {
+		c := component[v]
+		sizes[c]++
+		if sizes[c] > sizes[largest] {
+			largest = c
+		}
+	}
+
+	diameter := 0
+	for v := range vars {
+		if component[v] != largest {
+			continue
+		}
+		for _, d := range distances(outgoing, v) {
+			diameter = max(diameter, d)
+		}
+	}
+	return diameter
+}
+
+// AveragePathLength is the mean length, in links, of the shortest paths
+// between every ordered pair of distinct variables where the second is
+// reachable from the first.  Pairs in different components, or otherwise
+// unconnected, don't count, so each component contributes its own paths.
+// It is 0 for maps without links.
+func (m *Map) AveragePathLength() float64 {
+	outgoing := m.OutgoingEdges()
+
+	var total, paths int
+	for v := range m.Variables() {
+		for _, d := range distances(outgoing, v) {
+			total += d
+			paths++
+		}
+	}
+	if paths == 0 {
+		return 0
+	}
+	return float64(total) / float64(paths)
+}
+
+// MinimumFeedbackArcSet suggests where to intervene to break the map's
+// feedback loops: a small set of links whose removal leaves the map
+// acyclic.  Finding the smallest such set is NP-hard, so this uses the
+// greedy heuristic of Eades, Lin and Smyth, which orders the variables so
+// that few links point backwards, and returns those backward links.  For
+// each pair of variables, the first relationship between them is
+// returned.
+func (m *Map) MinimumFeedbackArcSet() []Relationship {
+	outgoing := make(map[string]Set[string])
+	incoming := make(map[string]Set[string])
+	for v := range m.Variables() {
+		outgoing[v] = make(Set[string])
+		incoming[v] = make(Set[string])
+	}
+	for from, tos := range m.OutgoingEdges() {
+		for _, to := range tos {
+			if from != to {
+				outgoing[from].Add(to)
+				incoming[to].Add(from)
+			}
+		}
+	}
+
+	remaining := m.Variables()
+	remove := func(v string) {
+		delete(remaining, v)
+		for to := range outgoing[v] {
+			delete(incoming[to], v)
+		}
+		for from := range incoming[v] {
+			delete(outgoing[from], v)
+		}
+	}
+
+	// variables are peeled off both ends: sinks onto the end of the
+	// order, sources onto the start, and when there are neither, the
+	// variable with the most surplus outgoing links onto the start
+	var head, tail []string
+	for len(remaining) > 0 {
+		peeled := false
+		for _, v := range remaining.Slice() {
+			if len(outgoing[v]) == 0 {
+				tail = append(tail, v)
+				remove(v)
+				peeled = true
+			}
+		}
+		for _, v := range remaining.Slice() {
+			if len(incoming[v]) == 0 {
+				head = append(head, v)
+				remove(v)
+				peeled = true
+			}
+		}
+		if peeled {
+			continue
+		}
+
+		var best string
+		bestDelta := 0
+		for _, v := range remaining.Slice() {
+			if delta := len(outgoing[v]) - len(incoming[v]); best == "" || delta > bestDelta {
+				best, bestDelta = v, delta
+			}
+		}
+		head = append(head, best)
+		remove(best)
+	}
+
+	slices.Reverse(tail)
+	position := make(map[string]int)
+	for i, v := range append(head, tail...) {
+		position[v] = i
+	}
+
+	var arcs []Relationship
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		if k := from + "\x00" + to; position[from] >= position[to] && !seen.Contains(k) {
+			seen.Add(k)
+			arcs = append(arcs, r)
+		}
+	}
+	return arcs
+}
+
+// ConnectednessReport describes how well a map hangs together.  A
+// coherent map is usually one large component with few isolated
+// variables; sources are its exogenous drivers, and sinks its outcomes.
+type ConnectednessReport struct {
+	// Components are the weakly connected components, each sorted, and
+	// ordered largest first, then by their first variable.
+	Components [][]string
+	// Isolated are the variables without any links.
+	Isolated []string
+	// Sources are the variables that have outgoing links but no
+	// incoming ones.
+	Sources []string
+	// Sinks are the variables that have incoming links but no outgoing
+	// ones.
+	Sinks []string
+	// LargestComponent is the number of variables in the largest
+	// component.
+	LargestComponent int
+}
+
+// ConnectednessReport diagnoses disconnected and dangling variables.
+func (m *Map) ConnectednessReport() ConnectednessReport {
+	vars := m.Variables()
+	outgoing := m.OutgoingEdges()
+	component := weakComponentOf(vars, outgoing)
+
+	hasIncoming := make(Set[string])
+	for _, tos := range outgoing {
+		for _, to := range tos {
+			hasIncoming.Add(to)
+		}
+	}
+
+	var report ConnectednessReport
+	grouped := make(map[int][]string)
+	for _, v := range vars.Slice() {
+		grouped[component[v]] = append(grouped[component[v]], v)
+
+		in, out := hasIncoming.Contains(v), len(outgoing[v]) > 0
+		switch {
+		case !in && !out:
+			report.Isolated = append(report.Isolated, v)
+		case !in:
+			report.Sources = append(report.Sources, v)
+		case !out:
+			report.Sinks = append(report.Sinks, v)
+		}
+	}
+
+	for _, group := range grouped {
+		report.Components = append(report.Components, group)
+		report.LargestComponent = max(report.LargestComponent, len(group))
+	}
+	slices.SortFunc(report.Components, func(a, b []string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a[0], b[0]))
+	})
+	return report
+}
+
+// StructuralDuplicates groups variables that have exactly the same causes
+// and the same effects.  Such variables are often the same concept under
+// different names, which FindVariable's name matching can't catch.
+// Variables without any links aren't grouped.  Each group is sorted, and
+// groups are ordered by their first variable.
+func (m *Map) StructuralDuplicates() [][]string {
+	outgoing := m.OutgoingEdges()
+	incoming := make(map[string]Set[string])
+	for from, tos := range outgoing {
+		for _, to := range tos {
+			if incoming[to] == nil {
+				incoming[to] = make(Set[string])
+			}
+			incoming[to].Add(from)
+		}
+	}
+
+	grouped := make(map[string][]string)
+	for _, v := range m.Variables().Slice() {
+		causes, effects := incoming[v].Slice(), NewSet(outgoing[v]...).Slice()
+		if len(causes) == 0 && len(effects) == 0 {
+			continue
+		}
+		k := strings.Join(causes, "\x00") + "\x00\x00" + strings.Join(effects, "\x00")
+		grouped[k] = append(grouped[k], v)
+	}
+
+	var duplicates [][]string
+	for _, vars := range grouped {
+		if len(vars) > 1 {
+			duplicates = append(duplicates, vars)
+		}
+	}
+	slices.SortFunc(duplicates, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return duplicates
+}
+
+// TrimToSize returns a summary of the map with at most n variables: the
+// n most central ones and the links among them.  Variables on more
+// feedback loops are kept first, so the core loops survive, then those
+// with more links, then by name.  A kept variable left with no links to
+// the others is dropped, as a map can't hold a variable on its own.
+func (m *Map) TrimToSize(n int) *Map {
+	loopCount := make(map[string]int)
+	for _, loop := range m.Loops() {
+		// loops repeat their first variable at the end
+		for _, v := range loop[1:] {
+			loopCount[v]++
+		}
+	}
+	degree := make(map[string]int)
+	for from, tos := range m.OutgoingEdges() {
+		for _, to := range NewSet(tos...).Slice() {
+			degree[from]++
+			degree[to]++
+		}
+	}
+
+	vars := m.Variables().Slice()
+	slices.SortStableFunc(vars, func(a, b string) int {
+		return cmp.Or(cmp.Compare(loopCount[b], loopCount[a]), cmp.Compare(degree[b], degree[a]))
+	})
+	kept := NewSet(vars[:min(max(n, 0), len(vars))]...)
+
+	var rels []Relationship
+	for _, r := range m.Relationships() {
+		if kept.Contains(normalizeVariable(r.From)) && kept.Contains(normalizeVariable(r.To)) {
+			rels = append(rels, r)
+		}
+	}
+
+	trimmed := NewMap(rels)
+	trimmed.Title = m.Title
+	trimmed.Explanation = m.Explanation
+	trimmed.Annotations = carryAnnotations(trimmed, []*Map{m}, normalizeVariable)
+	return trimmed
+}
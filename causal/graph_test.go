@@ -0,0 +1,194 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluenceScore(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Clashes", To: "Casualties", Polarity: "+"},
+	})
+
+	scores := m.InfluenceScore()
+	assert.Equal(t, 1.0, scores["tax burden"])
+	assert.Equal(t, 2.0/3.0, scores["tensions"])
+	assert.Equal(t, 0.0, scores["casualties"])
+	assert.Greater(t, scores["tax burden"], scores["casualties"])
+}
+
+func TestInfluences(t *testing.T) {
+	assert.Equal(t, []string{"resistance", "tensions"}, testMap1.Influences("Clashes"))
+	assert.Equal(t, []string{"resistance", "tensions"}, testMap1.InfluencedBy("clashes"))
+	assert.Equal(t, []string{"tensions"}, testMap1.InfluencedBy("Tax Burden"))
+	assert.Empty(t, testMap1.Influences("Weather"))
+}
+
+func TestLoopsOnly(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Weather", To: "Tax Burden", Polarity: "+"},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tax Burden", Polarity: "+"},
+		{From: "Clashes", To: "Casualties", Polarity: "+"},
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+	})
+
+	core := m.LoopsOnly()
+	assert.Equal(t, NewSet("tax burden", "tensions", "clashes"), core.Variables())
+	assert.Equal(t, 4, core.EdgeCount())
+	assert.Equal(t, m.Loops(), core.Loops())
+
+	assert.Empty(t, NewMap(m.Relationships()[:2]).LoopsOnly().Variables())
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	assert.Equal(t, [][]string{{"clashes", "resistance", "tax burden", "tensions"}}, testMap1.StronglyConnectedComponents())
+
+	m := NewMap(append(testMap1.Relationships(),
+		Relationship{From: "Weather", To: "Clashes", Polarity: "+"},
+		Relationship{From: "Clashes", To: "Casualties", Polarity: "+"},
+	))
+	assert.Equal(t, [][]string{
+		{"clashes", "resistance", "tax burden", "tensions"},
+		{"casualties"},
+		{"weather"},
+	}, m.StronglyConnectedComponents())
+}
+
+func TestDiameterAndAveragePathLength(t *testing.T) {
+	// the longest shortest path is resistance -> clashes -> tensions -> tax burden
+	assert.Equal(t, 3, testMap1.Diameter())
+	assert.InDelta(t, 1.5, testMap1.AveragePathLength(), 1e-9)
+
+	// a separate, smaller component doesn't affect the diameter, but its
+	// one path is averaged in
+	m := NewMap(append(testMap1.Relationships(), Relationship{From: "Weather", To: "Harvest", Polarity: "+"}))
+	assert.Equal(t, 3, m.Diameter())
+	assert.InDelta(t, 19.0/13, m.AveragePathLength(), 1e-9)
+
+	assert.Zero(t, NewMap(nil).Diameter())
+	assert.Zero(t, NewMap(nil).AveragePathLength())
+}
+
+func TestMinimumFeedbackArcSet(t *testing.T) {
+	arcs := testMap1.MinimumFeedbackArcSet()
+	assert.NotEmpty(t, arcs)
+
+	cut := make(Set[string])
+	for _, r := range arcs {
+		cut.Add(normalizeVariable(r.From) + "\x00" + normalizeVariable(r.To))
+	}
+	var rels []Relationship
+	for _, r := range testMap1.Relationships() {
+		if !cut.Contains(normalizeVariable(r.From) + "\x00" + normalizeVariable(r.To)) {
+			rels = append(rels, r)
+		}
+	}
+	remaining := NewMap(rels)
+	assert.Empty(t, remaining.Loops())
+	assert.True(t, remaining.Acyclic())
+	// testMap1 has three edge-disjoint loops of two variables each, so
+	// three links is optimal
+	assert.Len(t, arcs, 3)
+
+	assert.Empty(t, NewMap(testMap1.Relationships()[:2]).MinimumFeedbackArcSet())
+}
+
+func TestCouplingEdges(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Births", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Births", Polarity: "+"},
+		{From: "Population", To: "Pollution", Polarity: "+"},
+		{From: "Pollution", To: "Cleanup", Polarity: "+"},
+		{From: "Cleanup", To: "Pollution", Polarity: "-"},
+		{From: "Cleanup", To: "Taxes", Polarity: "+"},
+		{From: "Regulation", To: "Rules", Polarity: "+"},
+	})
+
+	coupling := m.CouplingEdges()
+	require.Len(t, coupling, 2)
+	assert.Equal(t, "Population", coupling[0].From)
+	assert.Equal(t, "Pollution", coupling[0].To)
+	assert.Equal(t, "Cleanup", coupling[1].From)
+	assert.Equal(t, "Taxes", coupling[1].To)
+
+	assert.Empty(t, testMap1.CouplingEdges())
+}
+
+func TestConnectednessReport(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Births", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Births", Polarity: "+"},
+		{From: "Food", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Pollution", Polarity: "+"},
+		{From: "Regulation", To: "Rules", Polarity: "+"},
+	})
+	m.CausalChains = append(m.CausalChains, Chain{InitialVariable: "Weather"})
+
+	assert.Equal(t, ConnectednessReport{
+		Components: [][]string{
+			{"births", "food", "pollution", "population"},
+			{"regulation", "rules"},
+			{"weather"},
+		},
+		Isolated:         []string{"weather"},
+		Sources:          []string{"food", "regulation"},
+		Sinks:            []string{"pollution", "rules"},
+		LargestComponent: 4,
+	}, m.ConnectednessReport())
+}
+
+func TestStructuralDuplicates(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Taxes", To: "Tensions", Polarity: "+"},
+		{From: "Taxes", To: "Anger", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Anger", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Clashes", To: "Anger", Polarity: "+"},
+		{From: "Clashes", To: "Resistance", Polarity: "+"},
+	})
+	m.CausalChains = append(m.CausalChains, Chain{InitialVariable: "Weather"}, Chain{InitialVariable: "Geography"})
+
+	assert.Equal(t, [][]string{{"anger", "tensions"}}, m.StructuralDuplicates())
+	assert.Empty(t, testMap1.StructuralDuplicates())
+}
+
+func TestTrimToSize(t *testing.T) {
+	m := NewMap(append(testMap1.Relationships(),
+		Relationship{From: "Tax Burden", To: "Smuggling", Polarity: "+"},
+		Relationship{From: "Smuggling", To: "Enforcement", Polarity: "+"},
+		Relationship{From: "Propaganda", To: "Tensions", Polarity: "+"},
+		Relationship{From: "Propaganda", To: "Resistance", Polarity: "+"},
+	))
+	m.Title = testMap1.Title
+
+	trimmed := m.TrimToSize(4)
+	assert.Equal(t, testMap1.Variables(), trimmed.Variables())
+	assert.Equal(t, testMap1.Loops(), trimmed.Loops())
+	assert.Equal(t, testMap1.Title, trimmed.Title)
+
+	trimmed = m.TrimToSize(2)
+	assert.LessOrEqual(t, trimmed.VariableCount(), 2)
+	assert.Equal(t, [][]string{{"clashes", "tensions", "clashes"}}, trimmed.Loops())
+
+	assert.Equal(t, m.Variables(), m.TrimToSize(100).Variables())
+	assert.Empty(t, m.TrimToSize(0).Variables())
+
+	// a kept variable with no links to the others is left out, rather
+	// than kept as an empty chain
+	m = NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Propaganda", To: "Resistance", Polarity: "+"},
+	})
+	trimmed = m.TrimToSize(3)
+	assert.Empty(t, trimmed.Validate())
+	assert.Equal(t, NewSet("tensions", "clashes"), trimmed.Variables())
+}
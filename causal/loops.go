@@ -0,0 +1,286 @@
+package causal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// NamedLoop is a feedback loop labeled the way system dynamicists
+// conventionally refer to them: R1, R2, ... for reinforcing loops and B1,
+// B2, ... for balancing ones.  Loops through a link of unknown ("?")
+// polarity are neither, and are labeled U1, U2, ....
+type NamedLoop struct {
+	ID        string
+	Variables []string
+	Polarity  Polarity
+}
+
+func (l NamedLoop) IsReinforcing() bool {
+	return l.Polarity.IsPositive()
+}
+
+// polarities maps each normalized (from, to) pair to the polarity of the
+// first edge between them.
+func (m *Map) polarities() map[[2]string]Polarity {
+	polarities := make(map[[2]string]Polarity)
+	for _, r := range m.Relationships() {
+		k := [2]string{normalizeVariable(r.From), normalizeVariable(r.To)}
+		if _, ok := polarities[k]; ok {
+			continue
+		}
+		switch {
+		case r.Polarity == "-":
+			polarities[k] = NegativePolarity
+		case isAmbiguousPolarity(r.Polarity):
+			polarities[k] = UnknownPolarity
+		default:
+			polarities[k] = PositivePolarity
+		}
+	}
+	return polarities
+}
+
+// loopPolarity is positive (reinforcing) when the loop has an even number
+// of negative links, and negative (balancing) otherwise.  It is unknown if
+// any link's polarity is.
+func loopPolarity(polarities map[[2]string]Polarity, loop []string) Polarity {
+	polarity := PositivePolarity
+	for i := 0; i+1 < len(loop); i++ {
+		link := polarities[[2]string{loop[i], loop[i+1]}]
+		if link.IsUnknown() {
+			return UnknownPolarity
+		}
+		if link.IsNegative() {
+			if polarity.IsPositive() {
+				polarity = NegativePolarity
+			} else {
+				polarity = PositivePolarity
+			}
+		}
+	}
+	return polarity
+}
+
+// LoopSort is an order for presenting feedback loops.
+type LoopSort int
+
+const (
+	// LoopSortLength orders loops shortest first, then by name; it is the
+	// order Loops returns them in.
+	LoopSortLength LoopSort = iota
+	// LoopSortPolarity lists reinforcing loops before balancing ones,
+	// and those of unknown polarity last, each shortest first.
+	LoopSortPolarity
+)
+
+// NamedLoops returns the same loops as Loops, in the same order, with
+// their polarity and an R/B identifier.
+func (m *Map) NamedLoops() []NamedLoop {
+	return m.SortLoops(LoopSortLength)
+}
+
+// SortLoops is NamedLoops in the given order.  Identifiers are numbered
+// in that order, too.
+func (m *Map) SortLoops(by LoopSort) []NamedLoop {
+	polarities := m.polarities()
+
+	var named []NamedLoop
+	for _, loop := range m.Loops() {
+		named = append(named, NamedLoop{
+			Variables: loop,
+			Polarity:  loopPolarity(polarities, loop),
+		})
+	}
+
+	if by == LoopSortPolarity {
+		// Loops is already ordered by length, so a stable sort keeps that
+		// order within each polarity
+		slices.SortStableFunc(named, func(a, b NamedLoop) int {
+			return cmp.Compare(polarityRank(a.Polarity), polarityRank(b.Polarity))
+		})
+	}
+
+	var nR, nB, nU int
+	for i := range named {
+		switch named[i].Polarity {
+		case PositivePolarity:
+			nR++
+			named[i].ID = fmt.Sprintf("R%d", nR)
+		case NegativePolarity:
+			nB++
+			named[i].ID = fmt.Sprintf("B%d", nB)
+		default:
+			nU++
+			named[i].ID = fmt.Sprintf("U%d", nU)
+		}
+	}
+	return named
+}
+
+// polarityRank orders loop polarities for LoopSortPolarity.
+func polarityRank(p Polarity) int {
+	switch p {
+	case PositivePolarity:
+		return 0
+	case NegativePolarity:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// loopKind describes a loop's polarity in words.
+func loopKind(l NamedLoop) string {
+	switch l.Polarity {
+	case PositivePolarity:
+		return "reinforcing"
+	case NegativePolarity:
+		return "balancing"
+	default:
+		return "of unknown polarity"
+	}
+}
+
+// LoopOverlap returns the variables shared by each pair of loops that
+// have any in common, keyed by the pair of loop IDs (in NamedLoops
+// order).  Loops that share variables interact, so their dynamics are
+// coupled.
+func (m *Map) LoopOverlap() map[[2]string][]string {
+	loops := m.NamedLoops()
+
+	overlap := make(map[[2]string][]string)
+	for i, a := range loops {
+		vars := NewSet(a.Variables...)
+		for _, b := range loops[i+1:] {
+			shared := make(Set[string])
+			for _, v := range b.Variables {
+				if vars.Contains(v) {
+					shared.Add(v)
+				}
+			}
+			if len(shared) > 0 {
+				overlap[[2]string{a.ID, b.ID}] = shared.Slice()
+			}
+		}
+	}
+	return overlap
+}
+
+// LoopContext returns the part of the map around the loop with the given
+// ID (as assigned by NamedLoops): the loop's variables, every variable
+// within radius links of them in either direction, and all relationships
+// among those variables.  It returns nil if there is no such loop.
+func (m *Map) LoopContext(loopID string, radius int) *Map {
+	i := slices.IndexFunc(m.NamedLoops(), func(l NamedLoop) bool {
+		return l.ID == loopID
+	})
+	if i < 0 {
+		return nil
+	}
+
+	neighbors := make(map[string][]string)
+	for from, tos := range m.OutgoingEdges() {
+		for _, to := range tos {
+			neighbors[from] = append(neighbors[from], to)
+			neighbors[to] = append(neighbors[to], from)
+		}
+	}
+
+	included := NewSet(m.NamedLoops()[i].Variables...)
+	frontier := included.Slice()
+	for range radius {
+		var next []string
+		for _, v := range frontier {
+			for _, n := range neighbors[v] {
+				if !included.Contains(n) {
+					included.Add(n)
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var rels []Relationship
+	for _, r := range m.Relationships() {
+		if included.Contains(normalizeVariable(r.From)) && included.Contains(normalizeVariable(r.To)) {
+			rels = append(rels, r)
+		}
+	}
+
+	local := NewMap(rels)
+	local.Title = m.Title
+	local.Explanation = m.Explanation
+	local.Annotations = carryAnnotations(local, []*Map{m}, normalizeVariable)
+	return local
+}
+
+// IncrementalLoops tracks the feedback loops of a map as relationships
+// are added to it one at a time, as when showing a map while it is being
+// generated.  Adding a link only searches for the loops through that
+// link, rather than re-searching the whole map.
+type IncrementalLoops struct {
+	outgoing map[string]Set[string]
+	loops    [][]string
+}
+
+// LoopsIncremental starts tracking loops from the map's current links.
+func (m *Map) LoopsIncremental() *IncrementalLoops {
+	l := &IncrementalLoops{outgoing: make(map[string]Set[string])}
+	for _, r := range m.Relationships() {
+		l.Add(r)
+	}
+	return l
+}
+
+// Add adds a link, returning the loops it closes, in the same form as
+// Loops.  Links that are already present close no new loops.
+func (l *IncrementalLoops) Add(r Relationship) [][]string {
+	from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+	if l.outgoing[from].Contains(to) {
+		return nil
+	}
+
+	// every new loop is the new link followed by a path back from its
+	// end to its start
+	var added [][]string
+	onPath := make(Set[string])
+	var search func(path []string, v string)
+	search = func(path []string, v string) {
+		path = append(path, v)
+		if v == from {
+			cycle := rotateCycle(path)
+			added = append(added, append(cycle, cycle[0]))
+			return
+		}
+		if len(path) >= DefaultMaxLoopDepth {
+			return
+		}
+		onPath.Add(v)
+		for _, next := range l.outgoing[v].Slice() {
+			if !onPath.Contains(next) {
+				search(path, next)
+			}
+		}
+		delete(onPath, v)
+	}
+	search(nil, to)
+
+	if l.outgoing[from] == nil {
+		l.outgoing[from] = make(Set[string])
+	}
+	l.outgoing[from].Add(to)
+
+	sortLoops(added)
+	l.loops = append(l.loops, added...)
+	return added
+}
+
+// Loops returns all the loops found so far, ordered as Loops orders
+// them.
+func (l *IncrementalLoops) Loops() [][]string {
+	loops := slices.Clone(l.loops)
+	sortLoops(loops)
+	return loops
+}
@@ -0,0 +1,130 @@
+package causal
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedLoops(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Population", To: "Births", Polarity: "+"},
+		{From: "Births", To: "Population", Polarity: "+"},
+		{From: "Population", To: "Deaths", Polarity: "+"},
+		{From: "Deaths", To: "Population", Polarity: "-"},
+	})
+
+	loops := m.NamedLoops()
+	assert.Equal(t, []NamedLoop{
+		{ID: "R1", Variables: []string{"births", "population", "births"}, Polarity: PositivePolarity},
+		{ID: "B1", Variables: []string{"deaths", "population", "deaths"}, Polarity: NegativePolarity},
+	}, loops)
+}
+
+func TestNamedLoopsUnknownPolarity(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Population", To: "Births", Polarity: "+"},
+		{From: "Births", To: "Population", Polarity: "?"},
+		{From: "Population", To: "Deaths", Polarity: "+"},
+		{From: "Deaths", To: "Population", Polarity: "-"},
+	})
+
+	loops := m.SortLoops(LoopSortPolarity)
+	assert.Equal(t, []NamedLoop{
+		{ID: "B1", Variables: []string{"deaths", "population", "deaths"}, Polarity: NegativePolarity},
+		{ID: "U1", Variables: []string{"births", "population", "births"}, Polarity: UnknownPolarity},
+	}, loops)
+	assert.False(t, loops[1].IsReinforcing())
+	assert.Contains(t, m.MarkdownReport(), "**U1** (of unknown polarity): More Births leads to a change in Population, which leads to a change in Births, so whether the loop amplifies or counteracts change is unknown.")
+}
+
+func TestSortLoopsByPolarity(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Inventory", To: "Price", Polarity: "-"},
+		{From: "Price", To: "Inventory", Polarity: "+"},
+		{From: "Adoption", To: "Word of Mouth", Polarity: "+"},
+		{From: "Word of Mouth", To: "Demand", Polarity: "+"},
+		{From: "Demand", To: "Adoption", Polarity: "+"},
+	})
+
+	assert.Equal(t, "B1", m.NamedLoops()[0].ID)
+
+	loops := m.SortLoops(LoopSortPolarity)
+	assert.Equal(t, []NamedLoop{
+		{ID: "R1", Variables: []string{"adoption", "word of mouth", "demand", "adoption"}, Polarity: PositivePolarity},
+		{ID: "B1", Variables: []string{"inventory", "price", "inventory"}, Polarity: NegativePolarity},
+	}, loops)
+}
+
+func TestLoopOverlap(t *testing.T) {
+	overlap := testMap1.LoopOverlap()
+	assert.Equal(t, map[[2]string][]string{
+		{"R1", "R2"}: {"clashes"},
+		{"R1", "R4"}: {"clashes", "resistance"},
+		{"R2", "R3"}: {"tensions"},
+		{"R2", "R4"}: {"clashes", "tensions"},
+		{"R3", "R4"}: {"tax burden", "tensions"},
+	}, overlap)
+}
+
+func TestFindLoopsDepthGuard(t *testing.T) {
+	const n = 24
+	var rels []Relationship
+	for i := range n {
+		for j := range n {
+			if i != j {
+				rels = append(rels, Relationship{From: fmt.Sprintf("v%02d", i), To: fmt.Sprintf("v%02d", j), Polarity: "+"})
+			}
+		}
+	}
+	m := NewMap(rels)
+
+	loops, truncated := m.FindLoops(4)
+	assert.True(t, truncated)
+	assert.NotEmpty(t, loops)
+	for _, loop := range loops {
+		assert.LessOrEqual(t, len(loop), 5)
+	}
+
+	_, truncated = testMap1.FindLoops(DefaultMaxLoopDepth)
+	assert.False(t, truncated)
+}
+
+func TestLoopContext(t *testing.T) {
+	loop := testMap1.LoopContext("R1", 0)
+	assert.Equal(t, []string{"clashes", "resistance"}, loop.Variables().Slice())
+	assert.Len(t, loop.Relationships(), 2)
+
+	ctx := testMap1.LoopContext("R1", 1)
+	assert.Equal(t, []string{"clashes", "resistance", "tax burden", "tensions"}, ctx.Variables().Slice())
+	assert.Equal(t, testMap1.Title, ctx.Title)
+	assert.Len(t, ctx.Relationships(), len(testMap1.Relationships()))
+
+	assert.Nil(t, testMap1.LoopContext("B9", 1))
+}
+
+func TestLoopsIncremental(t *testing.T) {
+	rels := testMap1.Relationships()
+
+	l := (&Map{}).LoopsIncremental()
+	for i, r := range rels {
+		l.Add(r)
+		assert.Equal(t, NewMap(rels[:i+1]).Loops(), l.Loops(), "after %d links", i+1)
+	}
+	assert.Equal(t, testMap1.Loops(), l.Loops())
+	assert.Equal(t, testMap1.Loops(), testMap1.LoopsIncremental().Loops())
+
+	l = (&Map{}).LoopsIncremental()
+	assert.Empty(t, l.Add(Relationship{From: "Population", To: "Births", Polarity: "+"}))
+	assert.Equal(t, [][]string{{"births", "population", "births"}}, l.Add(Relationship{From: "Births", To: "Population", Polarity: "+"}))
+	assert.Empty(t, l.Add(Relationship{From: "births", To: "population", Polarity: "+"}))
+	assert.Empty(t, l.Add(Relationship{From: "Population", To: "Deaths", Polarity: "+"}))
+	assert.Equal(t, [][]string{{"deaths", "population", "deaths"}}, l.Add(Relationship{From: "Deaths", To: "Population", Polarity: "-"}))
+	assert.Equal(t, [][]string{{"aging", "aging"}}, l.Add(Relationship{From: "Aging", To: "Aging", Polarity: "+"}))
+	assert.Equal(t, [][]string{
+		{"aging", "aging"},
+		{"births", "population", "births"},
+		{"deaths", "population", "deaths"},
+	}, l.Loops())
+}
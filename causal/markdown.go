@@ -0,0 +1,136 @@
+package causal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MermaidClickCallback is the JavaScript function that Mermaid charts
+// call when a variable with a description is clicked; the description is
+// also shown as the variable's tooltip.  Pages embedding a chart can
+// define it to respond to clicks.
+const MermaidClickCallback = "showVariable"
+
+// Mermaid renders the map as a Mermaid flowchart, one node per variable
+// and one edge, labeled with its polarity, per distinct link.
+func (m *Map) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	names := m.displayNames()
+	ids := make(map[string]string)
+	for i, v := range m.Variables().Slice() {
+		ids[v] = fmt.Sprintf("v%d", i)
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[v], strings.ReplaceAll(names[v], `"`, "#quot;"))
+	}
+
+	seen := make(Set[string])
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		edge := fmt.Sprintf("    %s -->|%s| %s\n", ids[from], r.Polarity, ids[to])
+		if !seen.Contains(edge) {
+			seen.Add(edge)
+			b.WriteString(edge)
+		}
+	}
+
+	descriptions := m.VariableDescriptions()
+	for _, v := range m.Variables().Slice() {
+		if d, ok := descriptions[v]; ok {
+			fmt.Fprintf(&b, "    click %s %s \"%s\"\n", ids[v], MermaidClickCallback, strings.ReplaceAll(d, `"`, "#quot;"))
+		}
+	}
+
+	return b.String()
+}
+
+// loopStory narrates a loop, following a change around it: "More A leads
+// to more B, which leads to less C, which leads to less A."  Past a link
+// of unknown polarity, the direction of the change is unknown too.
+func loopStory(polarities map[[2]string]Polarity, names map[string]string, l NamedLoop) string {
+	more, known := true, true
+	direction := func() string {
+		switch {
+		case !known:
+			return "a change in"
+		case more:
+			return "more"
+		default:
+			return "less"
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "More %s leads to ", names[l.Variables[0]])
+	for i := 1; i < len(l.Variables); i++ {
+		switch polarities[[2]string{l.Variables[i-1], l.Variables[i]}] {
+		case NegativePolarity:
+			more = !more
+		case UnknownPolarity:
+			known = false
+		}
+		if i > 1 {
+			b.WriteString(", which leads to ")
+		}
+		fmt.Fprintf(&b, "%s %s", direction(), names[l.Variables[i]])
+	}
+	switch l.Polarity {
+	case PositivePolarity:
+		b.WriteString(", so the loop amplifies any change.")
+	case NegativePolarity:
+		b.WriteString(", so the loop counteracts any change.")
+	default:
+		b.WriteString(", so whether the loop amplifies or counteracts change is unknown.")
+	}
+	return b.String()
+}
+
+// markdownCell escapes text for use in a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// MarkdownReport writes the map up as a Markdown document for
+// documentation pipelines: YAML front matter with the title, the
+// explanation, a Mermaid diagram, a table of the variables and what they
+// cause and are caused by, and a numbered list of the feedback loops with
+// a short story for each.
+func (m *Map) MarkdownReport() string {
+	var b strings.Builder
+
+	if m.Title != "" {
+		fmt.Fprintf(&b, "---\ntitle: %q\n---\n\n# %s\n\n", m.Title, m.Title)
+	}
+	if m.Explanation != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Explanation)
+	}
+
+	b.WriteString("## Diagram\n\n```mermaid\n")
+	b.WriteString(m.Mermaid())
+	b.WriteString("```\n\n")
+
+	names := m.displayNames()
+	display := func(vars []string) string {
+		for i, v := range vars {
+			vars[i] = names[v]
+		}
+		return markdownCell(strings.Join(vars, ", "))
+	}
+
+	b.WriteString("## Variables\n\n| Variable | Caused by | Affects |\n| --- | --- | --- |\n")
+	for _, v := range m.Variables().Slice() {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(names[v]), display(m.InfluencedBy(v)), display(m.Influences(v)))
+	}
+
+	b.WriteString("\n## Feedback loops\n\n")
+	loops := m.NamedLoops()
+	if len(loops) == 0 {
+		b.WriteString("No feedback loops detected — this is an open causal chain.\n")
+	}
+	polarities := m.polarities()
+	for i, l := range loops {
+		fmt.Fprintf(&b, "%d. **%s** (%s): %s\n", i+1, l.ID, loopKind(l), loopStory(polarities, names, l))
+	}
+
+	return b.String()
+}
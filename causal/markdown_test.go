@@ -0,0 +1,55 @@
+package causal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownReport(t *testing.T) {
+	report := testMap1.MarkdownReport()
+
+	assert.Contains(t, report, "---\ntitle: \"American Revolution Onset\"\n---\n")
+	assert.Contains(t, report, "```mermaid\nflowchart LR\n")
+	assert.Contains(t, report, `v0["Clashes"]`)
+	assert.Contains(t, report, "| Clashes | Resistance, Tensions | Resistance, Tensions |")
+	assert.Contains(t, report, "## Feedback loops")
+	assert.Contains(t, report, "1. **R1** (reinforcing): More Clashes leads to more Resistance, which leads to more Clashes, so the loop amplifies any change.")
+
+	m := NewMap([]Relationship{
+		{From: "Hunger", To: "Eating", Polarity: "+"},
+		{From: "Eating", To: "Hunger", Polarity: "-"},
+	})
+	assert.Contains(t, m.MarkdownReport(), "1. **B1** (balancing): More Eating leads to less Hunger, which leads to less Eating, so the loop counteracts any change.")
+	assert.Contains(t, NewMap(m.Relationships()[:1]).MarkdownReport(), "No feedback loops detected")
+}
+
+func TestVariableDescriptions(t *testing.T) {
+	m := NewMap(testMap1.Relationships())
+	m.Descriptions = []VariableDescription{
+		{Name: "Tax Burden", Description: `The "cost" of British taxes to colonists.`},
+		{Name: "tensions", Description: "Hostility between colonists and British authorities."},
+		{Name: "Tensions", Description: "A second description, ignored."},
+		{Name: "Weather", Description: "Not in the map."},
+	}
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	parsed, err := ParseMap(data)
+	require.NoError(t, err)
+	assert.Equal(t, m.Descriptions, parsed.Descriptions)
+
+	assert.Equal(t, map[string]string{
+		"tax burden": `The "cost" of British taxes to colonists.`,
+		"tensions":   "Hostility between colonists and British authorities.",
+	}, parsed.VariableDescriptions())
+
+	mermaid := parsed.Mermaid()
+	assert.Contains(t, mermaid, "    click v2 showVariable \"The #quot;cost#quot; of British taxes to colonists.\"\n")
+	assert.Contains(t, mermaid, "    click v3 showVariable \"Hostility between colonists and British authorities.\"\n")
+	assert.NotContains(t, mermaid, "click v0")
+
+	assert.NotContains(t, testMap1.Mermaid(), "click")
+}
@@ -0,0 +1,171 @@
+package causal
+
+import (
+	"maps"
+	"slices"
+)
+
+// MergeOptions controls how an ensemble of maps is combined.
+type MergeOptions struct {
+	// MinVotes is the number of input maps that must contain an edge
+	// (with the same polarity) for it to appear in the merged map.
+	// Values less than 1 are treated as 1.  With Weights, it is the
+	// total weight of the maps containing the edge that must reach it.
+	MinVotes int
+	// Weights are how much each input map's vote counts, by position,
+	// so that maps from more trusted models can count for more.  Maps
+	// without a weight count once.
+	Weights []float64
+	// Aliases maps variable names to the name they should be merged
+	// under, e.g. "Tension" -> "Tensions".  Matching is
+	// case-insensitive.
+	Aliases map[string]string
+	// MinSimilarity, if set, is how similar (by edit distance, 1 being
+	// identical) a name must be to one already seen for the two to be
+	// merged, so that "Tension" and "Tensions" don't split the vote even
+	// when they aren't listed in Aliases.  Zero turns this off.  Names
+	// that appear together in any one input are never merged, as that
+	// map treats them as different variables.  Use it with care: "Birth
+	// Rate" and "Death Rate" are 0.8 similar.
+	MinSimilarity float64
+}
+
+type edgeKey struct {
+	from, to, polarity string
+}
+
+// Merge combines the edges of several maps (typically generated from the
+// same prompt by several runs or models), keeping only the edges that
+// enough of the inputs agree on.  Variables are resolved through the
+// alias map, and optionally folded into a similar name already seen (see
+// MergeOptions.MinSimilarity), before voting.  Each kept edge comes from
+// the first input that has it, and keeps its chain's reasoning.
+func Merge(inputs []*Map, opts MergeOptions) *Map {
+	aliases := make(map[string]string, len(opts.Aliases))
+	for alias, canonical := range opts.Aliases {
+		aliases[normalizeVariable(alias)] = canonical
+	}
+	alias := func(name string) string {
+		if canonical, ok := aliases[normalizeVariable(name)]; ok {
+			return canonical
+		}
+		return name
+	}
+
+	// inputVars is each input's variables after aliasing, so that two
+	// names one map uses side by side are never folded together
+	inputVars := make([]Set[string], len(inputs))
+	for i, m := range inputs {
+		inputVars[i] = make(Set[string])
+		for v := range m.Variables() {
+			inputVars[i].Add(normalizeVariable(alias(v)))
+		}
+	}
+	together := func(a, b string) bool {
+		for _, vars := range inputVars {
+			if vars.Contains(a) && vars.Contains(b) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the first spelling we see for a variable is the one we keep
+	names := make(map[string]string)
+	folded := make(map[string]string)
+	resolve := func(name string) string {
+		name = alias(name)
+		key := normalizeVariable(name)
+		if _, ok := names[key]; ok {
+			return key
+		}
+		if k, ok := folded[key]; ok {
+			return k
+		}
+		if opts.MinSimilarity > 0 {
+			var candidates []string
+			for _, k := range slices.Sorted(maps.Keys(names)) {
+				if !together(key, k) {
+					candidates = append(candidates, k)
+				}
+			}
+			if closest, ok := closestVariable(key, candidates); ok && similarity(key, closest) >= opts.MinSimilarity {
+				folded[key] = closest
+				return closest
+			}
+		}
+		names[key] = name
+		return key
+	}
+
+	votes := make(map[edgeKey]float64)
+	for i, m := range inputs {
+		weight := 1.0
+		if i < len(opts.Weights) {
+			weight = opts.Weights[i]
+		}
+
+		seen := make(map[edgeKey]bool)
+		for _, r := range m.Relationships() {
+			k := edgeKey{from: resolve(r.From), to: resolve(r.To), polarity: r.Polarity}
+			// a single map only gets one vote per edge
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			votes[k] += weight
+		}
+	}
+
+	minVotes := float64(max(opts.MinVotes, 1))
+
+	// walk the chains again, keeping the first of each edge with enough
+	// votes, and splitting chains where one is dropped, as
+	// ResolveParallelEdges does
+	merged := &Map{}
+	emitted := make(map[edgeKey]bool)
+	for _, m := range inputs {
+		for _, chain := range m.CausalChains {
+			from := resolve(chain.InitialVariable)
+			piece := Chain{InitialVariable: names[from], Reasoning: chain.Reasoning}
+			for _, entry := range chain.Relationships {
+				to := resolve(entry.Variable)
+				k := edgeKey{from: from, to: to, polarity: entry.Polarity}
+				if votes[k] >= minVotes && !emitted[k] {
+					emitted[k] = true
+					entry.Variable = names[to]
+					piece.Relationships = append(piece.Relationships, entry)
+				} else {
+					if len(piece.Relationships) > 0 {
+						merged.CausalChains = append(merged.CausalChains, piece)
+					}
+					piece = Chain{InitialVariable: names[to], Reasoning: chain.Reasoning}
+				}
+				from = to
+			}
+			if len(piece.Relationships) > 0 {
+				merged.CausalChains = append(merged.CausalChains, piece)
+			}
+		}
+	}
+
+	merged.Annotations = carryAnnotations(merged, inputs, resolve)
+	if len(inputs) > 0 {
+		merged.Title = inputs[0].Title
+		merged.Explanation = inputs[0].Explanation
+	}
+	return merged
+}
+
+// VariableFrequency counts how many of the maps (typically an ensemble,
+// as for Merge) each normalized variable appears in.  Variables most of
+// the maps agree on are robust; those in only one are suspect.
+func VariableFrequency(maps []*Map) map[string]int {
+	frequency := make(map[string]int)
+	for _, m := range maps {
+		for v := range m.Variables() {
+			frequency[v]++
+		}
+	}
+	return frequency
+}
@@ -0,0 +1,150 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeVotes(t *testing.T) {
+	a := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+	})
+	b := NewMap([]Relationship{
+		{From: "tensions", To: "clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "-"},
+	})
+
+	merged := Merge([]*Map{a, b}, MergeOptions{MinVotes: 2})
+	rels := merged.Relationships()
+	assert.Len(t, rels, 1)
+	assert.Equal(t, "Tensions", rels[0].From)
+	assert.Equal(t, "Clashes", rels[0].To)
+
+	merged = Merge([]*Map{a, b}, MergeOptions{})
+	assert.Len(t, merged.Relationships(), 3)
+}
+
+func TestMergeAliases(t *testing.T) {
+	a := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	b := NewMap([]Relationship{
+		{From: "Tension", To: "Clashes", Polarity: "+"},
+	})
+	c := NewMap([]Relationship{
+		{From: "Resistance", To: "Clashes", Polarity: "+"},
+	})
+	maps := []*Map{a, b, c}
+
+	// names are only folded together when asked
+	merged := Merge(maps, MergeOptions{MinVotes: 2})
+	assert.Empty(t, merged.Relationships())
+
+	merged = Merge(maps, MergeOptions{MinVotes: 2, MinSimilarity: 0.85})
+	assert.Equal(t, []Relationship{{From: "Tensions", To: "Clashes", Polarity: "+"}}, merged.Relationships())
+
+	merged = Merge(maps, MergeOptions{
+		MinVotes: 2,
+		Aliases:  map[string]string{"Tension": "Tensions"},
+	})
+	assert.Equal(t, []Relationship{{From: "Tensions", To: "Clashes", Polarity: "+"}}, merged.Relationships())
+	assert.Equal(t, NewSet("tensions", "clashes"), merged.Variables())
+
+	// similar names for different things aren't
+	births := NewMap([]Relationship{{From: "Birth Rate", To: "Population", Polarity: "+"}})
+	deaths := NewMap([]Relationship{{From: "Death Rate", To: "Population", Polarity: "-"}})
+	merged = Merge([]*Map{births, deaths}, MergeOptions{MinSimilarity: 0.85})
+	assert.Equal(t, NewSet("birth rate", "death rate", "population"), merged.Variables())
+}
+
+func TestMergeKeepsNamesFromOneMapApart(t *testing.T) {
+	// "hiring rate" and "firing rate" are 0.9 similar, but a map that
+	// uses both means two different variables
+	m := NewMap([]Relationship{
+		{From: "Hiring Rate", To: "Workforce", Polarity: "+"},
+		{From: "Firing Rate", To: "Workforce", Polarity: "-"},
+	})
+	want := NewSet("hiring rate", "firing rate", "workforce")
+
+	assert.Equal(t, want, Merge([]*Map{m}, MergeOptions{}).Variables())
+	assert.Equal(t, want, Merge([]*Map{m}, MergeOptions{MinSimilarity: 0.85}).Variables())
+
+	// nor are they folded when another input has only one of them
+	hiring := NewMap([]Relationship{{From: "Hiring Rate", To: "Workforce", Polarity: "+"}})
+	merged := Merge([]*Map{hiring, m}, MergeOptions{MinSimilarity: 0.85})
+	assert.Equal(t, want, merged.Variables())
+	assert.Len(t, merged.Relationships(), 2)
+}
+
+func TestMergeKeepsReasoning(t *testing.T) {
+	a := &Map{CausalChains: []Chain{{
+		InitialVariable: "Tensions",
+		Relationships: []RelationshipEntry{
+			{Variable: "Clashes", Polarity: "+"},
+			{Variable: "Resistance", Polarity: "+"},
+		},
+		Reasoning: "Tensions boil over into clashes, which harden resistance.",
+	}}}
+	b := &Map{CausalChains: []Chain{{
+		InitialVariable: "tensions",
+		Relationships:   []RelationshipEntry{{Variable: "clashes", Polarity: "+"}},
+		Reasoning:       "Clashes follow tension.",
+	}}}
+
+	merged := Merge([]*Map{a, b}, MergeOptions{MinVotes: 2})
+	assert.Equal(t, []Relationship{{
+		From:      "Tensions",
+		To:        "Clashes",
+		Polarity:  "+",
+		Reasoning: "Tensions boil over into clashes, which harden resistance.",
+	}}, merged.Relationships())
+
+	merged = Merge([]*Map{a, b}, MergeOptions{})
+	assert.Equal(t, a.CausalChains, merged.CausalChains)
+}
+
+func TestMergeWeights(t *testing.T) {
+	big := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Resistance", To: "Clashes", Polarity: "+"},
+	})
+	small := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	maps := []*Map{big, small, small}
+
+	merged := Merge(maps, MergeOptions{MinVotes: 3})
+	assert.Equal(t, []Relationship{{From: "Tensions", To: "Clashes", Polarity: "+"}}, merged.Relationships())
+
+	merged = Merge(maps, MergeOptions{MinVotes: 3, Weights: []float64{3}})
+	assert.Equal(t, []Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Resistance", To: "Clashes", Polarity: "+"},
+	}, merged.Relationships())
+
+	merged = Merge(maps, MergeOptions{MinVotes: 2, Weights: []float64{0.5, 0.5, 0.5}})
+	assert.Empty(t, merged.Relationships())
+}
+
+func TestVariableFrequency(t *testing.T) {
+	a := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	b := NewMap([]Relationship{
+		{From: "tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Resistance", Polarity: "+"},
+	})
+	c := NewMap([]Relationship{
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+
+	assert.Equal(t, map[string]int{
+		"tensions":   3,
+		"clashes":    2,
+		"resistance": 1,
+		"tax burden": 1,
+	}, VariableFrequency([]*Map{a, b, c}))
+}
@@ -0,0 +1,209 @@
+package causal
+
+import (
+	"slices"
+	"strings"
+)
+
+type trailEdge struct {
+	from, to string
+	entry    RelationshipEntry
+	virtual  bool
+}
+
+// Minimal returns an equivalent map using as few chains as possible: each
+// distinct edge appears in exactly one chain, and chains are as long as
+// the graph allows (a minimum trail decomposition).  Duplicate edges are
+// dropped, and since chains are regrouped, their reasoning is not
+// preserved.  Variables and Loops are unchanged.
+func (m *Map) Minimal() *Map {
+	names := m.displayNames()
+
+	var edges []trailEdge
+	seen := make(map[edgeKey]bool)
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		k := edgeKey{from: from, to: to, polarity: r.Polarity}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		edges = append(edges, trailEdge{
+			from: from,
+			to:   to,
+			entry: RelationshipEntry{
+				Variable:          names[to],
+				Polarity:          r.Polarity,
+				PolarityReasoning: r.PolarityReasoning,
+				CreatedAt:         r.CreatedAt,
+				Source:            r.Source,
+				Evidence:          r.Evidence,
+				Confidence:        r.Confidence,
+			},
+		})
+	}
+
+	minimal := &Map{
+		Title:        m.Title,
+		Explanation:  m.Explanation,
+		Annotations:  slices.Clone(m.Annotations),
+		Descriptions: slices.Clone(m.Descriptions),
+	}
+
+	connected := make(Set[string])
+	for _, component := range weakComponents(edges) {
+		for _, e := range component {
+			connected.Add(e.from)
+			connected.Add(e.to)
+		}
+		for _, trail := range minimalTrails(component) {
+			chain := Chain{InitialVariable: names[trail[0].from]}
+			for _, e := range trail {
+				chain.Relationships = append(chain.Relationships, e.entry)
+			}
+			minimal.CausalChains = append(minimal.CausalChains, chain)
+		}
+	}
+
+	// keep variables that had no edges at all
+	for _, v := range m.Variables().Slice() {
+		if !connected.Contains(v) {
+			minimal.CausalChains = append(minimal.CausalChains, Chain{InitialVariable: names[v]})
+		}
+	}
+
+	return minimal
+}
+
+// weakComponents groups edges by the weakly connected component they are
+// in, ordered by the lowest-named variable in each.
+func weakComponents(edges []trailEdge) [][]trailEdge {
+	parent := make(map[string]string)
+	var find func(v string) string
+	find = func(v string) string {
+		if parent[v] == "" || parent[v] == v {
+			parent[v] = v
+			return v
+		}
+		root := find(parent[v])
+		parent[v] = root
+		return root
+	}
+	for _, e := range edges {
+		a, b := find(e.from), find(e.to)
+		if a != b {
+			// the lowest name is the root, so roots order components
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	byRoot := make(map[string][]trailEdge)
+	for _, e := range edges {
+		root := find(e.from)
+		byRoot[root] = append(byRoot[root], e)
+	}
+
+	roots := make([]string, 0, len(byRoot))
+	for root := range byRoot {
+		roots = append(roots, root)
+	}
+	slices.Sort(roots)
+
+	components := make([][]trailEdge, 0, len(roots))
+	for _, root := range roots {
+		components = append(components, byRoot[root])
+	}
+	return components
+}
+
+// minimalTrails covers a weakly connected set of edges with the fewest
+// trails.  Virtual edges are added from each variable with a surplus of
+// incoming edges to one with a surplus of outgoing edges, making the graph
+// Eulerian; the Euler circuit is then split at the virtual edges.
+func minimalTrails(edges []trailEdge) [][]trailEdge {
+	balance := make(map[string]int)
+	for _, e := range edges {
+		balance[e.from]++
+		balance[e.to]--
+	}
+
+	var sources, sinks []string
+	for v, b := range balance {
+		for range max(b, 0) {
+			sources = append(sources, v)
+		}
+		for range max(-b, 0) {
+			sinks = append(sinks, v)
+		}
+	}
+	slices.Sort(sources)
+	slices.Sort(sinks)
+
+	edges = slices.Clone(edges)
+	for i := range sources {
+		edges = append(edges, trailEdge{from: sinks[i], to: sources[i], virtual: true})
+	}
+
+	start := slices.MinFunc(edges, func(a, b trailEdge) int {
+		return strings.Compare(a.from, b.from)
+	}).from
+	if len(sources) > 0 {
+		start = sources[0]
+	}
+
+	circuit := eulerCircuit(edges, start)
+
+	// rotate the circuit so it starts just after a virtual edge, and
+	// every trail is then terminated by one
+	if i := slices.IndexFunc(circuit, func(e int) bool { return edges[e].virtual }); i >= 0 {
+		circuit = append(circuit[i+1:], circuit[:i+1]...)
+	}
+
+	var trails [][]trailEdge
+	var trail []trailEdge
+	for _, e := range circuit {
+		if edges[e].virtual {
+			trails = append(trails, trail)
+			trail = nil
+			continue
+		}
+		trail = append(trail, edges[e])
+	}
+	if len(trail) > 0 {
+		trails = append(trails, trail)
+	}
+	return trails
+}
+
+// eulerCircuit returns the indices of edges in the order they are visited
+// by an Euler circuit from start, using Hierholzer's algorithm.  Every
+// variable must have as many incoming as outgoing edges.
+func eulerCircuit(edges []trailEdge, start string) []int {
+	adjacent := make(map[string][]int)
+	for i, e := range edges {
+		adjacent[e.from] = append(adjacent[e.from], i)
+	}
+	next := make(map[string]int)
+
+	type frame struct {
+		v    string
+		edge int
+	}
+	stack := []frame{{v: start, edge: -1}}
+	var circuit []int
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if i := next[top.v]; i < len(adjacent[top.v]) {
+			next[top.v]++
+			e := adjacent[top.v][i]
+			stack = append(stack, frame{v: edges[e].to, edge: e})
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		if top.edge >= 0 {
+			circuit = append(circuit, top.edge)
+		}
+	}
+	slices.Reverse(circuit)
+	return circuit
+}
@@ -0,0 +1,63 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func edgeSet(m *Map) Set[string] {
+	edges := make(Set[string])
+	for _, r := range m.Relationships() {
+		edges.Add(normalizeVariable(r.From) + " -(" + r.Polarity + ")-> " + normalizeVariable(r.To))
+	}
+	return edges
+}
+
+func TestMinimal(t *testing.T) {
+	roadRage, err := ParseMap([]byte(roadRage1))
+	require.NoError(t, err)
+
+	minimal := roadRage.Minimal()
+	assert.Equal(t, edgeSet(roadRage), edgeSet(minimal))
+	assert.Len(t, minimal.Relationships(), len(edgeSet(roadRage)))
+	assert.Equal(t, roadRage.Variables(), minimal.Variables())
+	assert.Equal(t, roadRage.Loops(), minimal.Loops())
+	assert.Less(t, len(minimal.CausalChains), len(roadRage.CausalChains))
+
+	m := NewMap([]Relationship{
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+		{From: "Clashes", To: "Casualties", Polarity: "+"},
+	})
+	m.CausalChains = append(m.CausalChains, Chain{InitialVariable: "Weather"})
+
+	minimal = m.Minimal()
+	require.Len(t, minimal.CausalChains, 2)
+	assert.Len(t, minimal.CausalChains[0].Relationships, 5)
+	assert.Equal(t, "Weather", minimal.CausalChains[1].InitialVariable)
+	assert.Equal(t, edgeSet(m), edgeSet(minimal))
+	assert.Equal(t, m.Variables(), minimal.Variables())
+	assert.Equal(t, m.Loops(), minimal.Loops())
+}
+
+func TestFromRelationships(t *testing.T) {
+	rels := []Relationship{
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Casualties", Polarity: "+"},
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+	}
+
+	stitched := FromRelationships(rels)
+	naive := NewMap(rels)
+	assert.Len(t, stitched.CausalChains, 1)
+	assert.Greater(t, len(naive.CausalChains), 1)
+	assert.Equal(t, naive.Variables(), stitched.Variables())
+	assert.Equal(t, naive.Loops(), stitched.Loops())
+	assert.Equal(t, edgeSet(naive), edgeSet(stitched))
+}
@@ -0,0 +1,103 @@
+package causal
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Colors OverlayDOT draws edges in.
+const (
+	OverlayMatchColor   = "green"
+	OverlayMissingColor = "red"
+	OverlayExtraColor   = "orange"
+)
+
+// overlayEdge is a link in either map, with its polarity in each ("" if
+// absent).
+type overlayEdge struct {
+	from, to          string
+	reference, result string
+}
+
+// diffEdges pairs up the distinct links of the two maps, in sorted order.
+// Only the first polarity given for a link in each map counts.
+func diffEdges(reference, result *Map) []overlayEdge {
+	index := make(map[[2]string]int)
+	var edges []overlayEdge
+	edge := func(r Relationship) *overlayEdge {
+		k := [2]string{normalizeVariable(r.From), normalizeVariable(r.To)}
+		i, ok := index[k]
+		if !ok {
+			i = len(edges)
+			index[k] = i
+			edges = append(edges, overlayEdge{from: k[0], to: k[1]})
+		}
+		return &edges[i]
+	}
+	for _, r := range reference.Relationships() {
+		if e := edge(r); e.reference == "" {
+			e.reference = r.Polarity
+		}
+	}
+	for _, r := range result.Relationships() {
+		if e := edge(r); e.result == "" {
+			e.result = r.Polarity
+		}
+	}
+
+	slices.SortFunc(edges, func(a, b overlayEdge) int {
+		return cmp.Or(cmp.Compare(a.from, b.from), cmp.Compare(a.to, b.to))
+	})
+	return edges
+}
+
+// OverlayDOT renders a reference map and a generated result as one
+// Graphviz digraph, for evaluating the result by eye: links in both maps
+// are green, links missing from the result red, and extra links the
+// result added orange.  Links whose polarity differs are labeled with
+// both, the reference's first.
+func OverlayDOT(reference, result *Map) string {
+	var b strings.Builder
+
+	b.WriteString("digraph {\n\toverlap=false\n\tmode=KK\n")
+
+	names := reference.displayNames()
+	for v, name := range result.displayNames() {
+		if names[v] == "" {
+			names[v] = name
+		}
+	}
+	vars := reference.Variables()
+	for v := range result.Variables() {
+		vars.Add(v)
+	}
+	for _, v := range vars.Slice() {
+		fmt.Fprintf(&b, "\t%q [label=%q]\n", v, names[v])
+	}
+
+	for _, e := range diffEdges(reference, result) {
+		label, color := e.result, OverlayMatchColor
+		switch {
+		case e.result == "":
+			label, color = e.reference, OverlayMissingColor
+		case e.reference == "":
+			color = OverlayExtraColor
+		case e.reference != e.result:
+			label = e.reference + "/" + e.result
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q, color=%s, fontcolor=%s]\n", e.from, e.to, label, color, color)
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// OverlaySVG renders OverlayDOT to SVG with Graphviz.  As with VisualSVG,
+// the dot subprocess is killed if ctx is done first.
+func OverlaySVG(ctx context.Context, reference, result *Map) ([]byte, error) {
+	return visualSVG(ctx, renderSVG, OverlayDOT(reference, result))
+}
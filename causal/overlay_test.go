@@ -0,0 +1,27 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlayDOT(t *testing.T) {
+	reference := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	result := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Resistance", Polarity: "+"},
+	})
+
+	dot := OverlayDOT(reference, result)
+	assert.Contains(t, dot, `"tax burden" -> "tensions" [label="+", color=green, fontcolor=green]`)
+	assert.Contains(t, dot, `"tensions" -> "clashes" [label="+", color=red, fontcolor=red]`)
+	assert.Contains(t, dot, `"tensions" -> "resistance" [label="+", color=orange, fontcolor=orange]`)
+	assert.Contains(t, dot, `"resistance" [label="Resistance"]`)
+
+	result = NewMap([]Relationship{{From: "Tax Burden", To: "Tensions", Polarity: "-"}})
+	assert.Contains(t, OverlayDOT(reference, result), `"tax burden" -> "tensions" [label="+/-", color=green, fontcolor=green]`)
+}
@@ -0,0 +1,86 @@
+package causal
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParallelStrategy is how ResolveParallelEdges picks among relationships
+// that link the same variables with the same polarity.
+type ParallelStrategy int
+
+const (
+	// ParallelLongestReasoning keeps the relationship with the most
+	// reasoning (its chain's reasoning plus its polarity reasoning),
+	// and the first of those on a tie.
+	ParallelLongestReasoning ParallelStrategy = iota
+	// ParallelFirst keeps the first relationship.
+	ParallelFirst
+	// ParallelConcatenate keeps the first relationship, with the
+	// distinct polarity reasoning of all of them joined together.
+	// Reasoning belongs to a whole chain, so it isn't combined.
+	ParallelConcatenate
+)
+
+// ResolveParallelEdges returns a copy of m in which each link (from, to
+// and polarity) appears once, choosing among duplicates by strategy.
+// Chains are split where a duplicate is removed from their middle, so
+// every other relationship keeps its chain's reasoning.  Links between
+// the same variables with opposite polarities are left alone; see
+// Contradictions.
+func (m *Map) ResolveParallelEdges(strategy ParallelStrategy) *Map {
+	key := func(from, to, polarity string) edgeKey {
+		return edgeKey{from: normalizeVariable(from), to: normalizeVariable(to), polarity: polarity}
+	}
+	reasoningLength := func(r Relationship) int {
+		return len(strings.TrimSpace(r.Reasoning)) + len(strings.TrimSpace(r.PolarityReasoning))
+	}
+
+	// keep is the index, in Relationships order, of the one to keep
+	rels := m.Relationships()
+	keep := make(map[edgeKey]int)
+	polarityReasoning := make(map[edgeKey][]string)
+	for i, r := range rels {
+		k := key(r.From, r.To, r.Polarity)
+		if j, ok := keep[k]; !ok || (strategy == ParallelLongestReasoning && reasoningLength(r) > reasoningLength(rels[j])) {
+			keep[k] = i
+		}
+		if reason := strings.TrimSpace(r.PolarityReasoning); reason != "" && !slices.Contains(polarityReasoning[k], reason) {
+			polarityReasoning[k] = append(polarityReasoning[k], reason)
+		}
+	}
+
+	resolved := &Map{
+		Title:        m.Title,
+		Explanation:  m.Explanation,
+		Annotations:  slices.Clone(m.Annotations),
+		Descriptions: slices.Clone(m.Descriptions),
+	}
+
+	i := 0
+	for _, chain := range m.CausalChains {
+		piece := Chain{InitialVariable: chain.InitialVariable, Reasoning: chain.Reasoning}
+		from := chain.InitialVariable
+		for _, entry := range chain.Relationships {
+			k := key(from, entry.Variable, entry.Polarity)
+			if keep[k] == i {
+				if strategy == ParallelConcatenate {
+					entry.PolarityReasoning = strings.Join(polarityReasoning[k], " ")
+				}
+				piece.Relationships = append(piece.Relationships, entry)
+			} else {
+				if len(piece.Relationships) > 0 {
+					resolved.CausalChains = append(resolved.CausalChains, piece)
+				}
+				piece = Chain{InitialVariable: entry.Variable, Reasoning: chain.Reasoning}
+			}
+			from = entry.Variable
+			i++
+		}
+		if len(piece.Relationships) > 0 || len(chain.Relationships) == 0 {
+			resolved.CausalChains = append(resolved.CausalChains, piece)
+		}
+	}
+
+	return resolved
+}
@@ -0,0 +1,62 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveParallelEdges(t *testing.T) {
+	m := &Map{
+		Title: "Revolution",
+		CausalChains: []Chain{
+			{
+				InitialVariable: "Tax Burden",
+				Reasoning:       "Taxes anger colonists.",
+				Relationships: []RelationshipEntry{
+					{Variable: "Tensions", Polarity: "+", PolarityReasoning: "More taxes, more anger."},
+					{Variable: "Clashes", Polarity: "+", PolarityReasoning: "Anger boils over."},
+				},
+			},
+			{
+				InitialVariable: "Tax Burden",
+				Reasoning:       "The Stamp Act and Townshend Acts taxed everyday goods, and colonists without representation resented them.",
+				Relationships: []RelationshipEntry{
+					{Variable: "tensions", Polarity: "+", PolarityReasoning: "Taxation without representation."},
+				},
+			},
+			{
+				InitialVariable: "Tensions",
+				Reasoning:       "Unrest brings enforcement.",
+				Relationships: []RelationshipEntry{
+					{Variable: "Tax Burden", Polarity: "-", PolarityReasoning: "Britain backs down."},
+				},
+			},
+		},
+	}
+
+	first := m.ResolveParallelEdges(ParallelFirst)
+	assert.Equal(t, "Revolution", first.Title)
+	assert.Equal(t, m.CausalChains[0], first.CausalChains[0])
+	assert.Equal(t, m.CausalChains[2], first.CausalChains[1])
+	assert.Len(t, first.CausalChains, 2)
+
+	longest := m.ResolveParallelEdges(ParallelLongestReasoning)
+	require.Len(t, longest.CausalChains, 3)
+	// the duplicate was the start of the first chain, so the rest of it
+	// is split off
+	assert.Equal(t, Chain{
+		InitialVariable: "Tensions",
+		Reasoning:       "Taxes anger colonists.",
+		Relationships:   []RelationshipEntry{{Variable: "Clashes", Polarity: "+", PolarityReasoning: "Anger boils over."}},
+	}, longest.CausalChains[0])
+	assert.Equal(t, m.CausalChains[1], longest.CausalChains[1])
+	assert.Equal(t, m.CausalChains[2], longest.CausalChains[2])
+	assert.Equal(t, m.Variables(), longest.Variables())
+
+	concatenated := m.ResolveParallelEdges(ParallelConcatenate)
+	require.Len(t, concatenated.CausalChains, 2)
+	assert.Equal(t, "More taxes, more anger. Taxation without representation.", concatenated.CausalChains[0].Relationships[0].PolarityReasoning)
+	assert.Len(t, concatenated.Relationships(), 3)
+}
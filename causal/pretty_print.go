@@ -0,0 +1,88 @@
+package causal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PrettyPrint writes a plain-text rendering of the map, suitable for a
+// terminal: its variables, each relationship, and the feedback loops.
+func (m *Map) PrettyPrint(w io.Writer) error {
+	var b strings.Builder
+
+	if m.Title != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Title)
+	}
+
+	b.WriteString("Variables:\n")
+	for _, v := range m.Variables().Slice() {
+		fmt.Fprintf(&b, "  %s\n", v)
+	}
+
+	b.WriteString("\nRelationships:\n")
+	for _, r := range m.Relationships() {
+		fmt.Fprintf(&b, "  %s →(%s) %s\n", r.From, r.Polarity, r.To)
+	}
+
+	b.WriteString("\nFeedback loops:\n")
+	if m.Acyclic() {
+		b.WriteString("  No feedback loops detected — this is an open causal chain.\n")
+	}
+	for i, l := range m.NamedLoops() {
+		fmt.Fprintf(&b, "  %d. %s (%s): %s\n", i+1, l.ID, loopKind(l), strings.Join(l.Variables, " → "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ExplainVariable summarizes, in prose, what directly causes the variable
+// and what it in turn affects, followed by the reasoning for each of
+// those links.
+func (m *Map) ExplainVariable(name string) string {
+	v := normalizeVariable(name)
+	if !m.Variables().Contains(v) {
+		return fmt.Sprintf("%s does not appear in the map.", name)
+	}
+	names := m.displayNames()
+
+	var causes, effects, reasons []string
+	seen := make(map[edgeKey]bool)
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		k := edgeKey{from: from, to: to, polarity: r.Polarity}
+		if (from != v && to != v) || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		if to == v {
+			causes = append(causes, fmt.Sprintf("%s (%s)", names[from], r.Polarity))
+		}
+		if from == v {
+			effects = append(effects, fmt.Sprintf("%s (%s)", names[to], r.Polarity))
+		}
+		if reason := cmp.Or(r.PolarityReasoning, r.Reasoning); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("  %s →(%s) %s: %s\n", names[from], r.Polarity, names[to], reason))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(names[v])
+	if len(causes) > 0 {
+		fmt.Fprintf(&b, " is caused by %s", strings.Join(causes, ", "))
+	} else {
+		b.WriteString(" has no causes in the map")
+	}
+	if len(effects) > 0 {
+		fmt.Fprintf(&b, "; %s in turn affects %s.\n", names[v], strings.Join(effects, ", "))
+	} else {
+		fmt.Fprintf(&b, "; %s does not affect any other variable.\n", names[v])
+	}
+	for _, reason := range reasons {
+		b.WriteString(reason)
+	}
+	return b.String()
+}
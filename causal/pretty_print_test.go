@@ -0,0 +1,46 @@
+package causal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyPrint(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testMap1.PrettyPrint(&b))
+	out := b.String()
+
+	for _, v := range testMap1.Variables().Slice() {
+		assert.Contains(t, out, v)
+	}
+	assert.Contains(t, out, "Tax Burden →(+) Tensions")
+	assert.Contains(t, out, "1. R1 (reinforcing): clashes → resistance → clashes")
+}
+
+func TestPrettyPrintAcyclic(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	assert.True(t, m.Acyclic())
+	assert.False(t, testMap1.Acyclic())
+
+	var b strings.Builder
+	require.NoError(t, m.PrettyPrint(&b))
+	assert.Contains(t, b.String(), "No feedback loops detected — this is an open causal chain.")
+
+	b.Reset()
+	require.NoError(t, testMap1.PrettyPrint(&b))
+	assert.NotContains(t, b.String(), "No feedback loops detected")
+}
+
+func TestExplainVariable(t *testing.T) {
+	explanation := testMap1.ExplainVariable("tensions")
+	assert.Contains(t, explanation, "Tensions is caused by Tax Burden (+), Clashes (+); Tensions in turn affects Clashes (+), Tax Burden (+).")
+	assert.Contains(t, explanation, "Tax Burden →(+) Tensions: An increase in Tax Burden led to an increase in Tensions.")
+
+	assert.Equal(t, "Weather does not appear in the map.", testMap1.ExplainVariable("Weather"))
+}
@@ -0,0 +1,103 @@
+package causal
+
+import (
+	"strings"
+)
+
+// PruneOptions controls which leaf variables Prune removes.
+type PruneOptions struct {
+	// MinVariables is the size below which Prune stops removing
+	// variables.
+	MinVariables int
+	// MinReasoningLength is the number of characters of reasoning an
+	// edge needs to be kept.  Edges with no reasoning at all are always
+	// candidates for removal.
+	MinReasoningLength int
+}
+
+// weakReasoning reports whether neither the reasoning nor the polarity
+// reasoning of r meets the minimum length.
+func (opts PruneOptions) weakReasoning(r Relationship) bool {
+	minLen := max(opts.MinReasoningLength, 1)
+	return len(strings.TrimSpace(r.Reasoning)) < minLen &&
+		len(strings.TrimSpace(r.PolarityReasoning)) < minLen
+}
+
+// Prune iteratively removes leaf variables -- those connected to exactly
+// one other variable -- whose only edge is poorly reasoned, until none are
+// left or the map shrinks to opts.MinVariables.  Variables that are part
+// of a feedback loop are never removed.  m is not modified.
+func (m *Map) Prune(opts PruneOptions) *Map {
+	pruned := *m
+	pruned.CausalChains = cloneChains(m.CausalChains)
+
+	inLoop := make(Set[string])
+	for _, loop := range m.Loops() {
+		for _, v := range loop {
+			inLoop.Add(v)
+		}
+	}
+
+	for {
+		leaf, ok := pruned.weakLeaf(opts, inLoop)
+		if !ok || pruned.VariableCount() <= opts.MinVariables {
+			return &pruned
+		}
+		pruned.CausalChains = removeLeaf(pruned.CausalChains, leaf)
+	}
+}
+
+// weakLeaf returns the first (by name) variable that isn't in a loop,
+// has a single neighbor, and only poorly reasoned edges to it.
+func (m *Map) weakLeaf(opts PruneOptions, inLoop Set[string]) (string, bool) {
+	neighbors := make(map[string]Set[string])
+	weak := make(map[string]bool)
+	link := func(v, other string, r Relationship) {
+		if neighbors[v] == nil {
+			neighbors[v] = make(Set[string])
+			weak[v] = true
+		}
+		neighbors[v].Add(other)
+		weak[v] = weak[v] && opts.weakReasoning(r)
+	}
+	for _, r := range m.Relationships() {
+		from, to := normalizeVariable(r.From), normalizeVariable(r.To)
+		link(from, to, r)
+		link(to, from, r)
+	}
+
+	for _, v := range m.Variables().Slice() {
+		if !inLoop.Contains(v) && len(neighbors[v]) == 1 && weak[v] {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// removeLeaf drops the edges to the leaf variable v.  As v only has one
+// neighbor, it can only start or end a chain.
+func removeLeaf(chains []Chain, v string) []Chain {
+	var kept []Chain
+	for _, c := range chains {
+		if normalizeVariable(c.InitialVariable) == v {
+			c.InitialVariable = c.Relationships[0].Variable
+			c.Relationships = c.Relationships[1:]
+		}
+		if n := len(c.Relationships); n > 0 && normalizeVariable(c.Relationships[n-1].Variable) == v {
+			c.Relationships = c.Relationships[:n-1]
+		}
+		if len(c.Relationships) > 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func cloneChains(chains []Chain) []Chain {
+	cloned := make([]Chain, len(chains))
+	for i, c := range chains {
+		cloned[i] = c
+		cloned[i].Relationships = append([]RelationshipEntry(nil), c.Relationships...)
+	}
+	return cloned
+}
@@ -0,0 +1,29 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneKeepsLoopMembers(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Tax Burden", Polarity: "+"},
+		{From: "Tensions", To: "Weather", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+", PolarityReasoning: "Rising tensions made confrontations more likely."},
+		{From: "Rumors", To: "Gossip", Polarity: "+"},
+		{From: "Gossip", To: "Tensions", Polarity: "+"},
+	})
+
+	pruned := m.Prune(PruneOptions{MinReasoningLength: 10})
+	assert.Equal(t, NewSet("tax burden", "tensions", "clashes"), pruned.Variables())
+	assert.Equal(t, m.Loops(), pruned.Loops())
+
+	// the original map is untouched
+	assert.Equal(t, 6, m.VariableCount())
+
+	floored := m.Prune(PruneOptions{MinVariables: 5, MinReasoningLength: 10})
+	assert.Equal(t, 5, floored.VariableCount())
+	assert.False(t, floored.Variables().Contains("rumors"))
+}
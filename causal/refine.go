@@ -0,0 +1,90 @@
+package causal
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+//go:embed refine_prompt.txt
+var refinePrompt string
+
+// refinedEdgeSchema is the response schema for RefineEdge.
+var refinedEdgeSchema = &schema.JSON{
+	Type: schema.Object,
+	Properties: map[string]*schema.JSON{
+		"polarity":           {Type: schema.String, Enum: []string{"+", "-"}},
+		"polarity_reasoning": {Type: schema.String, Description: "Why a change in the first variable causes this change in the second."},
+	},
+	Required: []string{"polarity", "polarity_reasoning"},
+}
+
+// RefineEdge returns a copy of m in which the relationship from -> to
+// (matched case-insensitively) has the polarity and reasoning the model
+// settled on when asked to reconsider just that relationship, with the
+// rest of the map as context.  Everything else is unchanged.
+func (d diagrammer) RefineEdge(ctx context.Context, m *Map, from, to string) (*Map, error) {
+	fromKey, toKey := normalizeVariable(from), normalizeVariable(to)
+
+	var current *Relationship
+	for _, r := range m.Relationships() {
+		if normalizeVariable(r.From) == fromKey && normalizeVariable(r.To) == toKey {
+			current = &r
+			break
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no relationship from %q to %q in the map", from, to)
+	}
+
+	responseSchema, err := json.MarshalIndent(refinedEdgeSchema, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	opts := []chat.Option{
+		chat.WithResponseFormat("refined_relationship", true, refinedEdgeSchema),
+		chat.WithSystemPrompt(strings.ReplaceAll(refinePrompt, "{schema}", string(responseSchema))),
+	}
+	opts = append(opts, d.samplingOptions()...)
+
+	var b strings.Builder
+	if err := m.PrettyPrint(&b); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "\nReconsider the relationship %s →(%s) %s.", current.From, current.Polarity, current.To)
+	if reason := current.PolarityReasoning; reason != "" {
+		fmt.Fprintf(&b, "  Its current reasoning is: %s", reason)
+	}
+
+	content, _, err := d.completion(ctx, []chat.Message{{Role: chat.UserRole, Content: b.String()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var refined struct {
+		Polarity          string `json:"polarity"`
+		PolarityReasoning string `json:"polarity_reasoning"`
+	}
+	if err := json.Unmarshal([]byte(content), &refined); err != nil {
+		d.metrics.IncFailure(chat.FailureParse)
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	refinedMap := *m
+	refinedMap.CausalChains = cloneChains(m.CausalChains)
+	for _, c := range refinedMap.CausalChains {
+		prev := c.InitialVariable
+		for i, r := range c.Relationships {
+			if normalizeVariable(prev) == fromKey && normalizeVariable(r.Variable) == toKey {
+				c.Relationships[i].Polarity = refined.Polarity
+				c.Relationships[i].PolarityReasoning = refined.PolarityReasoning
+			}
+			prev = r.Variable
+		}
+	}
+	return &refinedMap, nil
+}
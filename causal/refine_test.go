@@ -0,0 +1,37 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefineEdge(t *testing.T) {
+	client := &mockClient{contents: []string{`{"polarity": "+", "polarity_reasoning": "Each new tax gave colonists another grievance against Britain."}`}}
+
+	refined, err := NewDiagrammer(client).RefineEdge(context.Background(), testMap1, "tax burden", "TENSIONS")
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 1)
+	request := client.calls[0][0].Content
+	assert.Contains(t, request, "Reconsider the relationship Tax Burden →(+) Tensions.  Its current reasoning is: An increase in Tax Burden led to an increase in Tensions.")
+	assert.Contains(t, request, "Clashes →(+) Resistance")
+
+	before, after := testMap1.Relationships(), refined.Relationships()
+	require.Len(t, after, len(before))
+	for i := range before {
+		if before[i].From == "Tax Burden" && before[i].To == "Tensions" {
+			assert.Equal(t, "Each new tax gave colonists another grievance against Britain.", after[i].PolarityReasoning)
+			assert.Equal(t, before[i].Reasoning, after[i].Reasoning)
+		} else {
+			assert.Equal(t, before[i], after[i])
+		}
+	}
+	assert.Equal(t, testMap1.Title, refined.Title)
+
+	_, err = NewDiagrammer(client).RefineEdge(context.Background(), testMap1, "Weather", "Tensions")
+	assert.ErrorContains(t, err, `no relationship from "Weather" to "Tensions"`)
+	assert.Len(t, client.calls, 1)
+}
@@ -0,0 +1,138 @@
+package causal
+
+import (
+	"maps"
+	"slices"
+)
+
+// Contradiction is a pair of variables linked with both polarities.
+type Contradiction struct {
+	From, To string
+}
+
+// RenameVariable returns a copy of m with the variable old renamed to
+// newName (both matched case-insensitively).  If newName already exists
+// the two variables are merged: duplicate edges are dropped, as are edges
+// between them that would become self-loops, and any pair of variables now
+// linked with both polarities is reported as a contradiction.  The
+// merged variable keeps newName's description and position, if it had
+// them, and every remaining relationship keeps its chain's reasoning.
+func (m *Map) RenameVariable(old, newName string) (*Map, []Contradiction) {
+	oldKey := normalizeVariable(old)
+	return m.renameVariables(func(name string) string {
+		if normalizeVariable(name) == oldKey {
+			return newName
+		}
+		return name
+	})
+}
+
+// renameVariables is RenameVariable for any number of variables at once:
+// each name, as written, is replaced with rename(name).  Chains are
+// split where an edge is dropped, so every other relationship keeps its
+// chain's reasoning, and descriptions and positions follow their
+// variables.
+func (m *Map) renameVariables(rename func(string) string) (*Map, []Contradiction) {
+	renamed := &Map{
+		Title:       m.Title,
+		Explanation: m.Explanation,
+	}
+
+	seen := make(map[edgeKey]bool)
+	for _, chain := range cloneChains(m.CausalChains) {
+		chain.InitialVariable = rename(chain.InitialVariable)
+		piece := Chain{InitialVariable: chain.InitialVariable, Reasoning: chain.Reasoning}
+		from := normalizeVariable(chain.InitialVariable)
+		for _, entry := range chain.Relationships {
+			entry.Variable = rename(entry.Variable)
+			to := normalizeVariable(entry.Variable)
+			k := edgeKey{from: from, to: to, polarity: entry.Polarity}
+			if from != to && !seen[k] {
+				seen[k] = true
+				piece.Relationships = append(piece.Relationships, entry)
+			} else {
+				if len(piece.Relationships) > 0 {
+					renamed.CausalChains = append(renamed.CausalChains, piece)
+				}
+				piece = Chain{InitialVariable: entry.Variable, Reasoning: chain.Reasoning}
+			}
+			from = to
+		}
+		if len(piece.Relationships) > 0 || len(chain.Relationships) == 0 {
+			renamed.CausalChains = append(renamed.CausalChains, piece)
+		}
+	}
+
+	// a variable merged into another keeps the other's description and
+	// position, if it has one
+	kept := make(Set[string])
+	for _, d := range m.Descriptions {
+		if rename(d.Name) == d.Name {
+			kept.Add(normalizeVariable(d.Name))
+		}
+	}
+	described := make(Set[string])
+	for _, d := range m.Descriptions {
+		newName := rename(d.Name)
+		v := normalizeVariable(newName)
+		if described.Contains(v) || (newName != d.Name && kept.Contains(v)) {
+			continue
+		}
+		described.Add(v)
+		d.Name = newName
+		renamed.Descriptions = append(renamed.Descriptions, d)
+	}
+
+	if m.Positions != nil {
+		renamed.Positions = make(map[string]Position, len(m.Positions))
+		for name, p := range m.Positions {
+			if rename(name) == name {
+				renamed.Positions[name] = p
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(m.Positions)) {
+			if newName := rename(name); newName != name && !hasPositionFold(renamed.Positions, newName) {
+				renamed.Positions[newName] = m.Positions[name]
+			}
+		}
+	}
+
+	renamed.Annotations = carryAnnotations(renamed, []*Map{m}, func(name string) string {
+		return normalizeVariable(rename(name))
+	})
+	return renamed, renamed.Contradictions()
+}
+
+// hasPositionFold reports whether positions has an entry for name,
+// ignoring case.
+func hasPositionFold(positions map[string]Position, name string) bool {
+	for k := range positions {
+		if normalizeVariable(k) == normalizeVariable(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contradictions returns each pair of variables linked with both
+// polarities, in the order the pairs first appear.
+func (m *Map) Contradictions() []Contradiction {
+	polarities := make(map[[2]string]Set[string])
+	var pairs [][2]string
+	for _, r := range m.Relationships() {
+		pair := [2]string{normalizeVariable(r.From), normalizeVariable(r.To)}
+		if polarities[pair] == nil {
+			polarities[pair] = make(Set[string])
+			pairs = append(pairs, pair)
+		}
+		polarities[pair].Add(r.Polarity)
+	}
+
+	var contradictions []Contradiction
+	for _, pair := range pairs {
+		if len(polarities[pair]) > 1 {
+			contradictions = append(contradictions, Contradiction{From: pair[0], To: pair[1]})
+		}
+	}
+	return contradictions
+}
@@ -0,0 +1,80 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameVariableMerges(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tax Burden", To: "Tension", Polarity: "+"},
+		{From: "Tension", To: "Tensions", Polarity: "+"},
+		{From: "Tension", To: "Clashes", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+	})
+
+	renamed, contradictions := m.RenameVariable("tension", "Tensions")
+	assert.Empty(t, contradictions)
+	assert.Equal(t, []Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+		{From: "Clashes", To: "Tensions", Polarity: "+"},
+	}, renamed.Relationships())
+	assert.Equal(t, NewSet("tax burden", "tensions", "clashes"), renamed.Variables())
+
+	m = NewMap([]Relationship{
+		{From: "Tension", To: "Clashes", Polarity: "-"},
+		{From: "Tensions", To: "Clashes", Polarity: "+"},
+	})
+	_, contradictions = m.RenameVariable("Tension", "Tensions")
+	assert.Equal(t, []Contradiction{{From: "tensions", To: "clashes"}}, contradictions)
+}
+
+func TestRenameVariableKeepsDetails(t *testing.T) {
+	m := &Map{
+		CausalChains: []Chain{{
+			InitialVariable: "Tax Burden",
+			Relationships: []RelationshipEntry{
+				{Variable: "Tension", Polarity: "+"},
+				{Variable: "Tensions", Polarity: "+"},
+				{Variable: "Clashes", Polarity: "+"},
+			},
+			Reasoning: "Taxes build tension, which boils over into clashes.",
+		}},
+		Descriptions: []VariableDescription{
+			{Name: "Tension", Description: "Unrest in the province."},
+			{Name: "Tensions", Description: "Unrest."},
+		},
+		Positions: map[string]Position{
+			"Tension":  {X: 1, Y: 1},
+			"Tensions": {X: 2, Y: 2},
+			"Clashes":  {X: 3, Y: 3},
+		},
+	}
+
+	renamed, _ := m.RenameVariable("tension", "Tensions")
+	// the self-loop splits the chain, but both halves keep its reasoning
+	assert.Equal(t, []Chain{
+		{
+			InitialVariable: "Tax Burden",
+			Relationships:   []RelationshipEntry{{Variable: "Tensions", Polarity: "+"}},
+			Reasoning:       "Taxes build tension, which boils over into clashes.",
+		},
+		{
+			InitialVariable: "Tensions",
+			Relationships:   []RelationshipEntry{{Variable: "Clashes", Polarity: "+"}},
+			Reasoning:       "Taxes build tension, which boils over into clashes.",
+		},
+	}, renamed.CausalChains)
+	assert.Equal(t, []VariableDescription{{Name: "Tensions", Description: "Unrest."}}, renamed.Descriptions)
+	assert.Equal(t, map[string]Position{
+		"Tensions": {X: 2, Y: 2},
+		"Clashes":  {X: 3, Y: 3},
+	}, renamed.Positions)
+
+	// the original is untouched
+	assert.Equal(t, "Tension", m.CausalChains[0].Relationships[0].Variable)
+}
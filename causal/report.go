@@ -0,0 +1,60 @@
+package causal
+
+import (
+	"context"
+)
+
+// GenerationReport is the full quality-assurance picture of a generated
+// map, for callers that want to decide what to do about problems rather
+// than have the diagrammer retry.
+type GenerationReport struct {
+	// Problems are structural problems with the map; see Validate.
+	Problems []string
+	// Violations are the ways the map fails the diagrammer's
+	// constraints; see Constraints.Violations.
+	Violations []string
+	// Contradictions are pairs of variables linked with both polarities.
+	Contradictions []Contradiction
+	// Ungrounded are the variables that don't appear in the background
+	// knowledge; see UngroundedVariables.  Without background knowledge
+	// there is nothing to check against, so it is empty.
+	Ungrounded []string
+	// PolarityMismatches are the relationships whose polarity reasoning
+	// reads as the opposite of their polarity; see PolarityMismatches.
+	PolarityMismatches []Relationship
+}
+
+// OK reports whether no issues were found.
+func (r GenerationReport) OK() bool {
+	return len(r.Problems) == 0 && len(r.Violations) == 0 && len(r.Contradictions) == 0 && len(r.Ungrounded) == 0 && len(r.PolarityMismatches) == 0
+}
+
+// NewGenerationReport runs every check on m.
+func NewGenerationReport(m *Map, c Constraints, backgroundKnowledge string) GenerationReport {
+	report := GenerationReport{
+		Problems:           m.Validate(),
+		Violations:         c.Violations(m),
+		Contradictions:     m.Contradictions(),
+		PolarityMismatches: m.PolarityMismatches(),
+	}
+	if backgroundKnowledge != "" {
+		report.Ungrounded = m.UngroundedVariables(backgroundKnowledge)
+	}
+	return report
+}
+
+// GenerateWithReport generates once, without the repair loop, and reports
+// on the result, which is returned as the model produced it (after any
+// post-processing).
+func (d diagrammer) GenerateWithReport(ctx context.Context, prompt, backgroundKnowledge string) (*Map, GenerationReport, error) {
+	d.maxRepairs = 0
+	m, _, err := d.GenerateRaw(ctx, prompt, backgroundKnowledge)
+	if err != nil {
+		return nil, GenerationReport{}, err
+	}
+	if m.DryRun != nil {
+		return m, GenerationReport{Problems: m.DryRun.Problems}, nil
+	}
+
+	return m, NewGenerationReport(m, d.constraints, backgroundKnowledge), nil
+}
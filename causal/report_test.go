@@ -0,0 +1,46 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateWithReport(t *testing.T) {
+	m := &Map{CausalChains: []Chain{
+		{
+			InitialVariable: "Tax Burden",
+			Relationships: []RelationshipEntry{
+				{Variable: "Tensions", Polarity: "+"},
+				{Variable: "Sunspots", Polarity: "+", PolarityReasoning: "Tension reduces sunspots."},
+			},
+		},
+		{
+			InitialVariable: "Tax Burden",
+			Relationships:   []RelationshipEntry{{Variable: "Tensions", Polarity: "-"}},
+		},
+		{InitialVariable: "Clashes"},
+	}}
+	client := &mockClient{contents: []string{mustJSON(t, m)}}
+	background := "The colonists' taxes rose, and so did their tensions with Britain."
+
+	d := NewDiagrammer(client, WithConstraints(Constraints{MinFeedback: 1}))
+	result, report, err := d.GenerateWithReport(context.Background(), "explain the revolution", background)
+	require.NoError(t, err)
+
+	// no repairs were attempted
+	assert.Len(t, client.calls, 1)
+	assert.Equal(t, m.Hash(), result.Hash())
+
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{`causal chain 2 starting at "Clashes" has no relationships`}, report.Problems)
+	assert.Equal(t, []string{"you returned 0 feedback loops but must return at least 1; add 1."}, report.Violations)
+	assert.Equal(t, []Contradiction{{From: "tax burden", To: "tensions"}}, report.Contradictions)
+	assert.Equal(t, []string{"clashes", "sunspots"}, report.Ungrounded)
+	require.Len(t, report.PolarityMismatches, 1)
+	assert.Equal(t, "Sunspots", report.PolarityMismatches[0].To)
+
+	assert.True(t, NewGenerationReport(testMap1, Constraints{}, "").OK())
+}
@@ -0,0 +1,87 @@
+package causal
+
+// PrecisionRecall measures how well a candidate set matches a reference
+// set.  Each is zero when undefined, e.g. precision for an empty
+// candidate.
+type PrecisionRecall struct {
+	// Precision is the fraction of the candidate's elements that are in
+	// the reference.
+	Precision float64
+	// Recall is the fraction of the reference's elements that are in the
+	// candidate.
+	Recall float64
+	// F1 is the harmonic mean of Precision and Recall.
+	F1 float64
+}
+
+func newPrecisionRecall(matched, candidate, reference int) PrecisionRecall {
+	var pr PrecisionRecall
+	if candidate > 0 {
+		pr.Precision = float64(matched) / float64(candidate)
+	}
+	if reference > 0 {
+		pr.Recall = float64(matched) / float64(reference)
+	}
+	if pr.Precision+pr.Recall > 0 {
+		pr.F1 = 2 * pr.Precision * pr.Recall / (pr.Precision + pr.Recall)
+	}
+	return pr
+}
+
+// ScoreReport grades a candidate map against a gold standard.
+type ScoreReport struct {
+	// Variables compares the (normalized) variables of the maps.
+	Variables PrecisionRecall
+	// Edges compares the links of the maps, regardless of polarity.
+	Edges PrecisionRecall
+	// PolarityAccuracy is the fraction of the links in both maps that
+	// have the same polarity in each.
+	PolarityAccuracy float64
+}
+
+// Score grades candidate against gold with partial credit, for comparing
+// how well models reproduce a known map.  Links are matched by their
+// normalized endpoints; where a map links two variables more than once,
+// the first link's polarity is the one compared.
+func Score(gold, candidate *Map) ScoreReport {
+	goldVars, candidateVars := gold.Variables(), candidate.Variables()
+	matchedVars := 0
+	for v := range candidateVars {
+		if goldVars.Contains(v) {
+			matchedVars++
+		}
+	}
+
+	goldEdges, candidateEdges := edgePolarities(gold), edgePolarities(candidate)
+	var matchedEdges, matchedPolarities int
+	for k, polarity := range candidateEdges {
+		if goldPolarity, ok := goldEdges[k]; ok {
+			matchedEdges++
+			if polarity == goldPolarity {
+				matchedPolarities++
+			}
+		}
+	}
+
+	report := ScoreReport{
+		Variables: newPrecisionRecall(matchedVars, len(candidateVars), len(goldVars)),
+		Edges:     newPrecisionRecall(matchedEdges, len(candidateEdges), len(goldEdges)),
+	}
+	if matchedEdges > 0 {
+		report.PolarityAccuracy = float64(matchedPolarities) / float64(matchedEdges)
+	}
+	return report
+}
+
+// edgePolarities maps each normalized (from, to) pair to the polarity of
+// the first link between them.
+func edgePolarities(m *Map) map[[2]string]string {
+	polarities := make(map[[2]string]string)
+	for _, r := range m.Relationships() {
+		k := [2]string{normalizeVariable(r.From), normalizeVariable(r.To)}
+		if _, ok := polarities[k]; !ok {
+			polarities[k] = r.Polarity
+		}
+	}
+	return polarities
+}
@@ -0,0 +1,31 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScore(t *testing.T) {
+	gold := testMap1.Relationships()
+	require.Len(t, gold, 7)
+
+	// drop the last link, and flip the polarity of the first
+	candidate := append([]Relationship(nil), gold[:6]...)
+	candidate[0].Polarity = "-"
+
+	report := Score(testMap1, NewMap(candidate))
+	assert.Equal(t, PrecisionRecall{Precision: 1, Recall: 1, F1: 1}, report.Variables)
+	assert.InDelta(t, 1, report.Edges.Precision, 1e-9)
+	assert.InDelta(t, 6.0/7, report.Edges.Recall, 1e-9)
+	assert.InDelta(t, 12.0/13, report.Edges.F1, 1e-9)
+	assert.InDelta(t, 5.0/6, report.PolarityAccuracy, 1e-9)
+
+	assert.Equal(t, ScoreReport{
+		Variables:        PrecisionRecall{Precision: 1, Recall: 1, F1: 1},
+		Edges:            PrecisionRecall{Precision: 1, Recall: 1, F1: 1},
+		PolarityAccuracy: 1,
+	}, Score(testMap1, testMap1))
+	assert.Equal(t, ScoreReport{}, Score(testMap1, &Map{}))
+}
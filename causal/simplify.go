@@ -0,0 +1,102 @@
+package causal
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SimplifyOptions configures Simplify.
+type SimplifyOptions struct {
+	// KeepDirect resolves each redundancy the other way: the direct edge
+	// is kept and the edges of the shortest multi-hop path it duplicates
+	// are dropped instead.
+	KeepDirect bool
+}
+
+// indirectPath returns the edges of a shortest path from -> to of at
+// least two hops, skipping removed edges, or nil if there is none.
+func indirectPath(outgoing map[string][]string, removed map[[2]string]bool, from, to string) [][2]string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, next := range outgoing[v] {
+			e := [2]string{v, next}
+			if e == [2]string{from, to} || removed[e] {
+				continue
+			}
+			if _, ok := prev[next]; ok {
+				continue
+			}
+			prev[next] = v
+			if next == to {
+				var path [][2]string
+				for w := to; w != from; w = prev[w] {
+					path = append(path, [2]string{prev[w], w})
+				}
+				slices.Reverse(path)
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// Simplify removes transitively redundant edges: a direct edge A → C is
+// redundant when there is also a path A → B → ... → C.  Dropping the
+// direct edge gives a cleaner diagram in which the same variables
+// influence each other, directly or not.  Edges that are part of a
+// feedback loop are never removed.
+func (m *Map) Simplify(opts SimplifyOptions) *Map {
+	outgoing := m.OutgoingEdges()
+	component := components(m.Variables(), outgoing)
+	inLoop := func(e [2]string) bool {
+		return component[e[0]] == component[e[1]]
+	}
+
+	var edges [][2]string
+	for from, tos := range outgoing {
+		for _, to := range tos {
+			edges = append(edges, [2]string{from, to})
+		}
+	}
+	// visit edges in sorted order so the result is deterministic
+	slices.SortFunc(edges, func(a, b [2]string) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+
+	removed := make(map[[2]string]bool)
+	for _, e := range edges {
+		if removed[e] || inLoop(e) {
+			continue
+		}
+		path := indirectPath(outgoing, removed, e[0], e[1])
+		if path == nil {
+			continue
+		}
+		if !opts.KeepDirect {
+			removed[e] = true
+			continue
+		}
+		for _, hop := range path {
+			if !inLoop(hop) {
+				removed[hop] = true
+			}
+		}
+	}
+
+	var rels []Relationship
+	for _, r := range m.Relationships() {
+		if !removed[[2]string{normalizeVariable(r.From), normalizeVariable(r.To)}] {
+			rels = append(rels, r)
+		}
+	}
+
+	simplified := NewMap(rels)
+	simplified.Title = m.Title
+	simplified.Explanation = m.Explanation
+	simplified.Annotations = carryAnnotations(simplified, []*Map{m}, normalizeVariable)
+	return simplified
+}
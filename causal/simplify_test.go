@@ -0,0 +1,39 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimplify(t *testing.T) {
+	triangle := NewMap([]Relationship{
+		{From: "Population", To: "Births", Polarity: "+"},
+		{From: "Births", To: "Housing Demand", Polarity: "+"},
+		{From: "Population", To: "Housing Demand", Polarity: "+"},
+	})
+	triangle.Title = "Housing"
+
+	simplified := triangle.Simplify(SimplifyOptions{})
+	assert.Equal(t, "Housing", simplified.Title)
+	assert.Equal(t, 2, simplified.EdgeCount())
+	assert.Equal(t, []string{"births"}, simplified.Influences("Population"))
+	assert.Equal(t, []string{"housing demand"}, simplified.Influences("Births"))
+
+	direct := triangle.Simplify(SimplifyOptions{KeepDirect: true})
+	assert.Equal(t, 1, direct.EdgeCount())
+	assert.Equal(t, []string{"housing demand"}, direct.Influences("Population"))
+}
+
+func TestSimplifyKeepsLoops(t *testing.T) {
+	// a -> c is redundant given a -> b -> c, but c -> a closes a loop
+	// through every edge, so nothing may be dropped
+	m := NewMap([]Relationship{
+		{From: "a", To: "b", Polarity: "+"},
+		{From: "b", To: "c", Polarity: "+"},
+		{From: "a", To: "c", Polarity: "+"},
+		{From: "c", To: "a", Polarity: "-"},
+	})
+	assert.Equal(t, m.EdgeCount(), m.Simplify(SimplifyOptions{}).EdgeCount())
+	assert.Equal(t, m.EdgeCount(), m.Simplify(SimplifyOptions{KeepDirect: true}).EdgeCount())
+}
@@ -2,7 +2,11 @@ package causal
 
 import (
 	"cmp"
+	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +14,9 @@ import (
 	"os/exec"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/isee-systems/sd-ai/chat"
 	"github.com/isee-systems/sd-ai/schema"
 )
 
@@ -22,6 +28,9 @@ type Polarity int
 const (
 	NegativePolarity Polarity = iota
 	PositivePolarity
+	// UnknownPolarity is the polarity of a link marked ambiguous ("?"),
+	// and of any loop through one.
+	UnknownPolarity
 )
 
 func (p Polarity) IsPositive() bool {
@@ -29,13 +38,19 @@ func (p Polarity) IsPositive() bool {
 }
 
 func (p Polarity) IsNegative() bool {
-	return !p.IsPositive()
+	return p == NegativePolarity
+}
+
+func (p Polarity) IsUnknown() bool {
+	return p == UnknownPolarity
 }
 
 func (p Polarity) Symbol() string {
 	switch p {
 	case PositivePolarity:
 		return "+"
+	case UnknownPolarity:
+		return AmbiguousPolarity
 	default:
 		return "-"
 	}
@@ -84,18 +99,50 @@ func init() {
 	}
 }
 
+// AmbiguousPolarity marks a relationship whose direction of influence the
+// model couldn't determine.
+const AmbiguousPolarity = "?"
+
+// isAmbiguousPolarity reports whether a relationship's polarity is
+// neither "+" nor "-" definitely.
+func isAmbiguousPolarity(polarity string) bool {
+	p := strings.ToLower(strings.TrimSpace(polarity))
+	return p == AmbiguousPolarity || p == "unknown"
+}
+
 type Relationship struct {
 	From              string `json:"from"`
 	To                string `json:"to"`
-	Polarity          string `json:"polarity"` // "+", or "-"
+	Polarity          string `json:"polarity"` // "+", "-", or "?"
 	Reasoning         string `json:"reasoning"`
 	PolarityReasoning string `json:"polarityReasoning"`
+
+	// CreatedAt and Source record when, and by what, the relationship
+	// was added to the map, if known.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Source    string    `json:"source,omitempty"`
+
+	// Evidence is whether the relationship is grounded in the background
+	// knowledge, if known; see TagEvidence.
+	Evidence Evidence `json:"evidence,omitempty"`
+
+	// Confidence is the model's confidence, from 0 to 1, that the
+	// relationship holds, if it gave one; see LowConfidenceEdges.
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 type RelationshipEntry struct {
 	Variable          string `json:"variable"`
-	Polarity          string `json:"polarity"` // "+", or "-"
-	PolarityReasoning string `json:"polarityReasoning"`
+	Polarity          string `json:"polarity"` // "+", "-", or "?"
+	PolarityReasoning string `json:"polarity_reasoning"`
+
+	// CreatedAt and Source are provenance metadata; see StampProvenance.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Source    string    `json:"source,omitempty"`
+	Evidence  Evidence  `json:"evidence,omitempty"`
+	// Confidence is nullable in the response schema, so it's nil if the
+	// model didn't give one.
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 type Chain struct {
@@ -104,36 +151,215 @@ type Chain struct {
 	Reasoning       string              `json:"reasoning"`
 }
 
+// VariableDescription is the model's explanation of what a variable
+// represents.
+type VariableDescription struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Position is where a variable is drawn, in points (1/72 inch), with y
+// increasing upwards as in Graphviz.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
 type Map struct {
 	Title        string  `json:"title"`
 	Explanation  string  `json:"explanation"`
 	CausalChains []Chain `json:"causal_chains"`
+	// Descriptions explain the variables, if the model was asked for
+	// them with WithVariableDescriptions; see VariableDescriptions.
+	Descriptions []VariableDescription `json:"variables,omitempty"`
+	// Positions are where variables were placed in an editor, keyed by
+	// name, to keep them there when the map is rendered; see
+	// WithLayoutHints.
+	Positions map[string]Position `json:"positions,omitempty"`
+	// Annotations are notes users attached to variables and edges; they
+	// never come from the model.
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	// RateLimit is the provider's remaining budget as of the response
+	// this map was generated from, if the client reported it.
+	RateLimit *chat.RateLimitInfo `json:"-"`
+	// DryRun is set instead of any content when the map came from a
+	// diagrammer in dry-run mode.
+	DryRun *DryRunReport `json:"-"`
+}
+
+// normalizeVariable is the canonical form variable names are compared in.
+func normalizeVariable(name string) string {
+	return strings.TrimSpace(strings.ToLower(name))
 }
 
 func (m *Map) Variables() (vars Set[string]) {
 	vars = make(Set[string])
 	for _, c := range m.CausalChains {
-		vars.Add(strings.TrimSpace(strings.ToLower(c.InitialVariable)))
+		vars.Add(normalizeVariable(c.InitialVariable))
+		for _, next := range c.Relationships {
+			vars.Add(normalizeVariable(next.Variable))
+		}
+	}
+	return vars
+}
+
+// VariableDescriptions maps each normalized variable name to its
+// description, for tooltips and documentation in exports.  Descriptions
+// of variables that aren't in the map are left out, and the first
+// description of a variable wins.
+func (m *Map) VariableDescriptions() map[string]string {
+	vars := m.Variables()
+	descriptions := make(map[string]string)
+	for _, d := range m.Descriptions {
+		v := normalizeVariable(d.Name)
+		if _, ok := descriptions[v]; ok || !vars.Contains(v) || d.Description == "" {
+			continue
+		}
+		descriptions[v] = d.Description
+	}
+	return descriptions
+}
+
+// VariablesInOrder returns the same (normalized) variables as Variables,
+// in the order they first appear in the causal chains rather than sorted.
+func (m *Map) VariablesInOrder() []string {
+	seen := make(Set[string])
+	var vars []string
+	add := func(name string) {
+		if v := normalizeVariable(name); !seen.Contains(v) {
+			seen.Add(v)
+			vars = append(vars, v)
+		}
+	}
+	for _, c := range m.CausalChains {
+		add(c.InitialVariable)
 		for _, next := range c.Relationships {
-			vars.Add(strings.TrimSpace(strings.ToLower(next.Variable)))
+			add(next.Variable)
 		}
 	}
 	return vars
 }
 
+// Relationships flattens the causal chains into individual edges.  Names
+// are returned as the model wrote them; each edge carries the reasoning of
+// the chain it came from.
+func (m *Map) Relationships() []Relationship {
+	var rels []Relationship
+	for _, chain := range m.CausalChains {
+		from := chain.InitialVariable
+		for _, r := range chain.Relationships {
+			rels = append(rels, Relationship{
+				From:              from,
+				To:                r.Variable,
+				Polarity:          r.Polarity,
+				Reasoning:         chain.Reasoning,
+				PolarityReasoning: r.PolarityReasoning,
+				CreatedAt:         r.CreatedAt,
+				Source:            r.Source,
+				Evidence:          r.Evidence,
+				Confidence:        r.Confidence,
+			})
+			from = r.Variable
+		}
+	}
+	return rels
+}
+
+// StripReasoning returns a copy of m with the reasoning of every chain and
+// relationship cleared, leaving just the structure.
+func (m *Map) StripReasoning() *Map {
+	stripped := *m
+	stripped.CausalChains = cloneChains(m.CausalChains)
+	stripped.Annotations = slices.Clone(m.Annotations)
+	for i := range stripped.CausalChains {
+		c := &stripped.CausalChains[i]
+		c.Reasoning = ""
+		for j := range c.Relationships {
+			c.Relationships[j].PolarityReasoning = ""
+		}
+	}
+	return &stripped
+}
+
+// Hash is a stable digest of the map's structure: its (normalized)
+// variables and distinct edges with their polarities.  Titles, reasoning
+// and how edges are grouped into chains don't affect it, so maps with the
+// same structure hash the same.
+func (m *Map) Hash() string {
+	edges := make(Set[string])
+	for _, r := range m.Relationships() {
+		edges.Add(normalizeVariable(r.From) + "\x00" + normalizeVariable(r.To) + "\x00" + r.Polarity)
+	}
+
+	h := sha256.New()
+	for _, v := range m.Variables().Slice() {
+		fmt.Fprintf(h, "v\x00%s\n", v)
+	}
+	for _, e := range edges.Slice() {
+		fmt.Fprintf(h, "e\x00%s\n", e)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StampProvenance records source and the time at as the provenance of
+// every relationship that doesn't have any yet.  Maps generated with
+// WithProvenance are stamped automatically; callers editing maps by hand
+// can stamp their additions.
+func (m *Map) StampProvenance(source string, at time.Time) {
+	for i := range m.CausalChains {
+		for j := range m.CausalChains[i].Relationships {
+			r := &m.CausalChains[i].Relationships[j]
+			if r.Source == "" && r.CreatedAt.IsZero() {
+				r.Source = source
+				r.CreatedAt = at
+			}
+		}
+	}
+}
+
+// VariableCount is the number of distinct (normalized) variables in the map.
+func (m *Map) VariableCount() int {
+	return len(m.Variables())
+}
+
+// EdgeCount is the number of distinct (normalized) from -> to edges in
+// the map; a link repeated across chains is only counted once.
+func (m *Map) EdgeCount() int {
+	edges := make(Set[string])
+	for from, tos := range m.OutgoingEdges() {
+		for _, to := range tos {
+			edges.Add(from + "\x00" + to)
+		}
+	}
+	return len(edges)
+}
+
+// DefaultMaxLoopDepth bounds the path length explored by Loops.  It is far
+// longer than any loop a person would want to read, but keeps the
+// recursive search from exhausting the stack on adversarial input.
+const DefaultMaxLoopDepth = 64
+
 type searchState struct {
-	edges   map[string][]string
-	visited Set[string]
-	found   [][]string
+	edges     map[string][]string
+	visited   Set[string]
+	found     [][]string
+	maxDepth  int
+	truncated bool
 }
 
-func (s *searchState) addCycle(path []string) {
+// rotateCycle rotates the variables of a cycle so that the lowest-named
+// is first.
+func rotateCycle(path []string) []string {
 	cycle := make([]string, 0, len(path))
-
-	// rotate the path so that the lowest-named variable is first
 	i := slices.Index(path, slices.Min(path))
 	cycle = append(cycle, path[i:]...)
 	cycle = append(cycle, path[:i]...)
+	return cycle
+}
+
+func (s *searchState) addCycle(path []string) {
+	cycle := rotateCycle(path)
 
 	for _, foundCycle := range s.found {
 		// already recorded it, nothing to do
@@ -146,6 +372,11 @@ func (s *searchState) addCycle(path []string) {
 }
 
 func (s *searchState) search(path []string, v string) {
+	if s.maxDepth > 0 && len(path) >= s.maxDepth {
+		s.truncated = true
+		return
+	}
+
 	s.visited.Add(v)
 	path = append(path, v)
 
@@ -160,10 +391,11 @@ func (s *searchState) search(path []string, v string) {
 	}
 }
 
-func findCycles(outgoing map[string][]string) (found [][]string) {
+func findCycles(outgoing map[string][]string, maxDepth int) (found [][]string, truncated bool) {
 	s := searchState{
-		edges:   outgoing,
-		visited: make(Set[string], len(outgoing)),
+		edges:    outgoing,
+		visited:  make(Set[string], len(outgoing)),
+		maxDepth: maxDepth,
 	}
 
 	for v := range outgoing {
@@ -173,11 +405,12 @@ func findCycles(outgoing map[string][]string) (found [][]string) {
 		s.search(path, v)
 	}
 
-	return s.found
+	return s.found, s.truncated
 }
 
-func (m *Map) Loops() [][]string {
-	// build a map of all outgoing edges in our diagram/graph.
+// OutgoingEdges maps each (normalized) variable to the variables it
+// directly influences.
+func (m *Map) OutgoingEdges() map[string][]string {
 	outgoing := make(map[string][]string)
 	for _, chain := range m.CausalChains {
 		for i, r := range chain.Relationships {
@@ -187,13 +420,25 @@ func (m *Map) Loops() [][]string {
 			} else {
 				from = chain.Relationships[i-1].Variable
 			}
-			from = strings.TrimSpace(strings.ToLower(from))
-			to := strings.TrimSpace(strings.ToLower(r.Variable))
+			from = normalizeVariable(from)
+			to := normalizeVariable(r.Variable)
 			outgoing[from] = append(outgoing[from], to)
 		}
 	}
+	return outgoing
+}
 
-	allLoops := findCycles(outgoing)
+func (m *Map) Loops() [][]string {
+	loops, _ := m.FindLoops(DefaultMaxLoopDepth)
+	return loops
+}
+
+// FindLoops is Loops with a configurable bound on the length of the paths
+// searched.  truncated reports whether the bound was hit, in which case
+// longer loops may be missing from the result.  A maxDepth of 0 means
+// unbounded.
+func (m *Map) FindLoops(maxDepth int) (loops [][]string, truncated bool) {
+	allLoops, truncated := findCycles(m.OutgoingEdges(), maxDepth)
 
 	// make the loops clearer by ensuring that we repeat as the last
 	// element the initial one.
@@ -201,7 +446,14 @@ func (m *Map) Loops() [][]string {
 		allLoops[i] = append(loop, loop[0])
 	}
 
-	slices.SortStableFunc(allLoops, func(a, b []string) int {
+	sortLoops(allLoops)
+
+	return allLoops, truncated
+}
+
+// sortLoops orders loops shortest first, then by name.
+func sortLoops(loops [][]string) {
+	slices.SortStableFunc(loops, func(a, b []string) int {
 		if len(a) < len(b) {
 			return -1
 		} else if len(a) > len(b) {
@@ -210,24 +462,39 @@ func (m *Map) Loops() [][]string {
 
 		return slices.Compare(a, b)
 	})
-
-	return allLoops
 }
 
-func (m *Map) VisualSVG() ([]byte, error) {
-	var b strings.Builder
+// VisualSVG renders the map to SVG with Graphviz.  The dot subprocess is
+// killed if ctx is done first, so callers should set a deadline.
+func (m *Map) VisualSVG(ctx context.Context, opts ...DOTOption) ([]byte, error) {
+	return visualSVG(ctx, renderSVG, m.DOT(opts...))
+}
 
-	b.WriteString("digraph {\n\toverlap=false\n\tmode=KK\n")
+// SVGDataURI renders the map as VisualSVG does, encoded as a base64 data
+// URI for use directly as the src of an HTML img.
+func (m *Map) SVGDataURI(ctx context.Context, opts ...DOTOption) (string, error) {
+	svg, err := m.VisualSVG(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	return svgDataURI(svg), nil
+}
 
-	// FIXME
-	//for _, r := range m.Relationships {
-	//	b.WriteString(fmt.Sprintf("\t%q -> %q\n", r.From, r.To))
-	//}
+func svgDataURI(svg []byte) string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(svg)
+}
 
-	b.WriteString("}\n")
+func visualSVG(ctx context.Context, render func(context.Context, string) ([]byte, error), dot string) ([]byte, error) {
+	svg, err := render(ctx, dot)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("dot rendering aborted: %w", ctx.Err())
+	}
+	return svg, err
+}
 
-	cmd := exec.Command("dot", "-Tsvg", "-Ksfdp")
-	cmd.Stdin = strings.NewReader(b.String())
+func renderSVG(ctx context.Context, dot string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "dot", "-Tsvg", "-Ksfdp")
+	cmd.Stdin = strings.NewReader(dot)
 	cmd.Stderr = os.Stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -250,21 +517,68 @@ func (m *Map) VisualSVG() ([]byte, error) {
 	return svg, nil
 }
 
+// ParseMap decodes a map from JSON in either the causal_chains shape
+// models are asked to produce, or as a flat list of relationships (with
+// from/to fields).  If both are present, the relationships are appended
+// as additional chains.  Each relationship in the flat shape becomes a
+// chain of its own, so that it keeps its reasoning.
+func ParseMap(data []byte) (*Map, error) {
+	var raw struct {
+		Map
+		Relationships []Relationship `json:"relationships"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	m := raw.Map
+	for _, r := range raw.Relationships {
+		chain := NewMap([]Relationship{r}).CausalChains[0]
+		chain.Reasoning = r.Reasoning
+		m.CausalChains = append(m.CausalChains, chain)
+	}
+	return &m, nil
+}
+
+// NewMap builds a map from a flat list of relationships.  Consecutive
+// relationships that form a path (A -> B followed by B -> C) are coalesced
+// into a single chain, the way a model would have written them.
 func NewMap(relationships []Relationship) *Map {
 	m := &Map{}
 
 	for _, r := range relationships {
+		entry := RelationshipEntry{
+			Variable:          r.To,
+			Polarity:          r.Polarity,
+			PolarityReasoning: r.PolarityReasoning,
+			CreatedAt:         r.CreatedAt,
+			Source:            r.Source,
+			Evidence:          r.Evidence,
+			Confidence:        r.Confidence,
+		}
+
+		if n := len(m.CausalChains); n > 0 {
+			last := &m.CausalChains[n-1]
+			tail := last.Relationships[len(last.Relationships)-1].Variable
+			if normalizeVariable(tail) == normalizeVariable(r.From) {
+				last.Relationships = append(last.Relationships, entry)
+				continue
+			}
+		}
+
 		m.CausalChains = append(m.CausalChains, Chain{
 			InitialVariable: r.From,
-			Relationships: []RelationshipEntry{
-				{
-					Variable:          r.To,
-					Polarity:          r.Polarity,
-					PolarityReasoning: r.PolarityReasoning,
-				},
-			},
+			Relationships:   []RelationshipEntry{entry},
 		})
 	}
 
 	return m
 }
+
+// FromRelationships builds a map from a flat list of relationships, such
+// as an imported edge list, stitching them into as few chains as
+// possible regardless of the order they're listed in.  Unlike NewMap,
+// duplicate relationships are dropped.
+func FromRelationships(relationships []Relationship) *Map {
+	return NewMap(relationships).Minimal()
+}
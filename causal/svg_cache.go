@@ -0,0 +1,56 @@
+package causal
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+)
+
+// SVGCache remembers rendered SVGs, so that re-rendering a map that
+// hasn't changed, as an interactive UI does on every minor update, doesn't
+// re-run Graphviz.  It is safe for concurrent use, and grows with each
+// distinct rendering; callers that render many maps should replace it
+// periodically.
+type SVGCache struct {
+	mu   sync.Mutex
+	svgs map[[sha256.Size]byte][]byte
+
+	// render produces the SVG for a DOT source; tests replace it to
+	// observe when Graphviz would run.
+	render func(ctx context.Context, dot string) ([]byte, error)
+}
+
+func NewSVGCache() *SVGCache {
+	return &SVGCache{
+		svgs:   make(map[[sha256.Size]byte][]byte),
+		render: renderSVG,
+	}
+}
+
+// VisualSVGCached is VisualSVG, reusing the SVG in cache when it has
+// rendered the same thing before.  Renderings are keyed by the digest of
+// the DOT source rather than by Hash, as that also covers the title,
+// annotations and DOT options, which change the picture without changing
+// the map's structure.  Failed renderings aren't cached.
+func (m *Map) VisualSVGCached(ctx context.Context, cache *SVGCache, opts ...DOTOption) ([]byte, error) {
+	dot := m.DOT(opts...)
+	key := sha256.Sum256([]byte(dot))
+
+	cache.mu.Lock()
+	svg, ok := cache.svgs[key]
+	cache.mu.Unlock()
+	if ok {
+		return svg, nil
+	}
+
+	svg, err := visualSVG(ctx, cache.render, dot)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	cache.svgs[key] = svg
+	cache.mu.Unlock()
+
+	return svg, nil
+}
@@ -0,0 +1,36 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisualSVGCached(t *testing.T) {
+	cache := NewSVGCache()
+	var renders int
+	cache.render = func(ctx context.Context, dot string) ([]byte, error) {
+		renders++
+		return []byte("<svg/>"), nil
+	}
+
+	ctx := context.Background()
+	svg, err := testMap1.VisualSVGCached(ctx, cache)
+	require.NoError(t, err)
+	assert.Equal(t, "<svg/>", string(svg))
+	assert.Equal(t, 1, renders)
+
+	// an unchanged map, even rebuilt from scratch, is served from the cache
+	_, err = NewMap(testMap1.Relationships()).VisualSVGCached(ctx, cache)
+	require.NoError(t, err)
+	_, err = testMap1.VisualSVGCached(ctx, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renders)
+
+	// but a different picture of it isn't
+	_, err = testMap1.VisualSVGCached(ctx, cache, WithLegend())
+	require.NoError(t, err)
+	assert.Equal(t, 2, renders)
+}
@@ -0,0 +1,55 @@
+package causal
+
+import (
+	"strings"
+)
+
+// articles and quantityOfPhrases are stripped from the start of variable
+// names by TidyNames.  They are matched case-insensitively, and articles
+// are tried again after the phrase ("The Level of the Economy").
+var (
+	articles          = []string{"the ", "a ", "an "}
+	quantityOfPhrases = []string{"level of ", "amount of ", "quantity of ", "degree of ", "extent of "}
+)
+
+// trimPrefixFold removes the first of prefixes that name starts with,
+// ignoring case.
+func trimPrefixFold(name string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			return strings.TrimSpace(name[len(prefix):])
+		}
+	}
+	return name
+}
+
+// tidyName strips a leading article and "level of"-style phrase from name.
+func tidyName(name string) string {
+	tidy := strings.TrimSpace(name)
+	tidy = trimPrefixFold(tidy, articles)
+	tidy = trimPrefixFold(tidy, quantityOfPhrases)
+	tidy = trimPrefixFold(tidy, articles)
+	return tidy
+}
+
+// TidyNames returns a copy of m with boilerplate stripped from the start
+// of variable names: leading articles and phrases like "level of" and
+// "amount of", so "The Level of Stress" becomes "Stress".  Variables that
+// end up with the same name are merged, as by RenameVariable.  It is
+// conservative, only ever removing whole leading words, and the renames
+// it made are returned, keyed by the original name as written, so they
+// can be reversed, along with any contradictions in the tidied map, as
+// from RenameVariable.
+func (m *Map) TidyNames() (*Map, map[string]string, []Contradiction) {
+	renamed := make(map[string]string)
+	for _, r := range m.Relationships() {
+		for _, name := range []string{r.From, r.To} {
+			if tidy := tidyName(name); tidy != name {
+				renamed[name] = tidy
+			}
+		}
+	}
+
+	tidied, contradictions := m.renameVariables(tidyName)
+	return tidied, renamed, contradictions
+}
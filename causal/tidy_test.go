@@ -0,0 +1,71 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTidyNames(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "The Level of Stress", To: "Amount of Sleep", Polarity: "-"},
+		{From: "Sleep", To: "Stress", Polarity: "-"},
+		{From: "stress", To: "An Apple a Day", Polarity: "-"},
+		{From: "Theater Visits", To: "Level Of The Economy", Polarity: "+"},
+	})
+	m.AnnotateVariable("The Level of Stress", "self-reported")
+
+	tidied, renamed, contradictions := m.TidyNames()
+	assert.Equal(t, NewSet("stress", "sleep", "apple a day", "theater visits", "economy"), tidied.Variables())
+	// the first two relationships are now duplicates
+	assert.Equal(t, 4, tidied.EdgeCount())
+	assert.Equal(t, []string{"self-reported"}, tidied.VariableNotes("Stress"))
+	assert.Equal(t, "Stress", tidied.displayNames()["stress"])
+
+	assert.Equal(t, map[string]string{
+		"The Level of Stress":  "Stress",
+		"Amount of Sleep":      "Sleep",
+		"An Apple a Day":       "Apple a Day",
+		"Level Of The Economy": "Economy",
+	}, renamed)
+	assert.Empty(t, contradictions)
+
+	// tidying is a no-op on tidy names, but still returns a copy
+	again, renamed, _ := tidied.TidyNames()
+	assert.Empty(t, renamed)
+	assert.Equal(t, tidied.Hash(), again.Hash())
+	assert.NotSame(t, tidied, again)
+
+	// merging variables can link a pair with both polarities
+	m = NewMap([]Relationship{
+		{From: "Level of Stress", To: "Sleep", Polarity: "-"},
+		{From: "Stress", To: "Sleep", Polarity: "+"},
+	})
+	_, _, contradictions = m.TidyNames()
+	assert.Equal(t, []Contradiction{{From: "stress", To: "sleep"}}, contradictions)
+}
+
+func TestTidyNamesKeepsReasoning(t *testing.T) {
+	m := &Map{
+		CausalChains: []Chain{{
+			InitialVariable: "The Level of Stress",
+			Relationships: []RelationshipEntry{
+				{Variable: "Amount of Sleep", Polarity: "-", PolarityReasoning: "Worry keeps people up."},
+				{Variable: "Stress", Polarity: "-"},
+			},
+			Reasoning: "Stress and sleep feed each other.",
+		}},
+		Descriptions: []VariableDescription{{Name: "Amount of Sleep", Description: "Hours a night."}},
+	}
+
+	tidied, _, _ := m.TidyNames()
+	assert.Equal(t, []Chain{{
+		InitialVariable: "Stress",
+		Relationships: []RelationshipEntry{
+			{Variable: "Sleep", Polarity: "-", PolarityReasoning: "Worry keeps people up."},
+			{Variable: "Stress", Polarity: "-"},
+		},
+		Reasoning: "Stress and sleep feed each other.",
+	}}, tidied.CausalChains)
+	assert.Equal(t, map[string]string{"sleep": "Hours a night."}, tidied.VariableDescriptions())
+}
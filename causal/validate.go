@@ -0,0 +1,79 @@
+package causal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Validate describes each structural problem with the map, such as a
+// chain that doesn't link its initial variable to anything.  A nil result
+// means the map is well formed.
+func (m *Map) Validate() []string {
+	var problems []string
+	for i, c := range m.CausalChains {
+		if len(c.Relationships) == 0 {
+			problems = append(problems, fmt.Sprintf("causal chain %d starting at %q has no relationships", i, c.InitialVariable))
+		}
+	}
+	return problems
+}
+
+// ValidateAgainstVocabulary describes each variable in the map that isn't
+// one of the allowed terms (compared case-insensitively).  For near
+// misses, the description suggests the closest allowed term.
+func (m *Map) ValidateAgainstVocabulary(allowed []string) []string {
+	terms := make(map[string]string, len(allowed))
+	for _, term := range allowed {
+		terms[normalizeVariable(term)] = term
+	}
+	candidates := make([]string, 0, len(terms))
+	for k := range terms {
+		candidates = append(candidates, k)
+	}
+	slices.Sort(candidates)
+
+	names := m.displayNames()
+
+	var problems []string
+	for _, v := range m.Variables().Slice() {
+		if _, ok := terms[v]; ok {
+			continue
+		}
+		if suggestion, ok := closestVariable(v, candidates); ok {
+			problems = append(problems, fmt.Sprintf("%q is not in the vocabulary; did you mean %q?", names[v], terms[suggestion]))
+		} else {
+			problems = append(problems, fmt.Sprintf("%q is not in the vocabulary", names[v]))
+		}
+	}
+	return problems
+}
+
+// CanonicalizeOptions controls the optional cleanups Canonicalize does.
+type CanonicalizeOptions struct {
+	// DropEmptyChains removes chains with no relationships, which
+	// otherwise show up as isolated variables.
+	DropEmptyChains bool
+}
+
+// Canonicalize returns a copy of m with surrounding whitespace trimmed
+// from variable names and polarities, plus any cleanups enabled in opts.
+func (m *Map) Canonicalize(opts CanonicalizeOptions) *Map {
+	canonical := *m
+	canonical.CausalChains = nil
+	canonical.Annotations = slices.Clone(m.Annotations)
+	for _, c := range m.CausalChains {
+		if opts.DropEmptyChains && len(c.Relationships) == 0 {
+			continue
+		}
+
+		c.InitialVariable = strings.TrimSpace(c.InitialVariable)
+		c.Relationships = append([]RelationshipEntry(nil), c.Relationships...)
+		for i := range c.Relationships {
+			c.Relationships[i].Variable = strings.TrimSpace(c.Relationships[i].Variable)
+			c.Relationships[i].Polarity = strings.TrimSpace(c.Relationships[i].Polarity)
+		}
+		canonical.CausalChains = append(canonical.CausalChains, c)
+	}
+	return &canonical
+}
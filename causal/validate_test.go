@@ -0,0 +1,43 @@
+package causal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEmptyChain(t *testing.T) {
+	assert.Empty(t, testMap1.Validate())
+
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+"},
+	})
+	m.CausalChains = append(m.CausalChains, Chain{InitialVariable: "Weather"})
+	assert.True(t, m.Variables().Contains("weather"))
+
+	problems := m.Validate()
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], `"Weather" has no relationships`)
+
+	kept := m.Canonicalize(CanonicalizeOptions{})
+	assert.Len(t, kept.CausalChains, 2)
+
+	cleaned := m.Canonicalize(CanonicalizeOptions{DropEmptyChains: true})
+	assert.Empty(t, cleaned.Validate())
+	assert.Equal(t, NewSet("tax burden", "tensions"), cleaned.Variables())
+	assert.Len(t, m.CausalChains, 2)
+}
+
+func TestValidateAgainstVocabulary(t *testing.T) {
+	vocabulary := []string{"Tax Burden", "Tensions", "Resistance", "Armed Clashes"}
+
+	problems := testMap1.ValidateAgainstVocabulary(vocabulary)
+	assert.Equal(t, []string{`"Clashes" is not in the vocabulary`}, problems)
+
+	m := NewMap([]Relationship{
+		{From: "tax burden", To: "Tension", Polarity: "+"},
+	})
+	problems = m.ValidateAgainstVocabulary(vocabulary)
+	assert.Equal(t, []string{`"Tension" is not in the vocabulary; did you mean "Tensions"?`}, problems)
+}
@@ -0,0 +1,202 @@
+package causal
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+//go:embed verify_prompt.txt
+var verifyPrompt string
+
+// verifyBatchSize is the number of relationships sent to the model in each
+// verification request.
+const verifyBatchSize = 20
+
+// polarityVerdictsSchema is the response schema for polarity
+// verification: the polarity the model believes correct for each
+// relationship, by id.
+var polarityVerdictsSchema = &schema.JSON{
+	Type: schema.Object,
+	Properties: map[string]*schema.JSON{
+		"verdicts": {
+			Type: schema.Array,
+			Items: &schema.JSON{
+				Type: schema.Object,
+				Properties: map[string]*schema.JSON{
+					"id":        {Type: schema.String, Description: "The id of the relationship, as given."},
+					"polarity":  {Type: schema.String, Enum: []string{"+", "-"}, Description: "The correct polarity of the relationship."},
+					"reasoning": {Type: schema.String, Description: "Why the polarity is correct."},
+				},
+				Required: []string{"id", "polarity", "reasoning"},
+			},
+		},
+	},
+	Required: []string{"verdicts"},
+}
+
+type polarityVerdict struct {
+	ID        string `json:"id"`
+	Polarity  string `json:"polarity"`
+	Reasoning string `json:"reasoning"`
+}
+
+// VerifyPolarities returns a copy of m in which every relationship the
+// model disagrees with has its polarity flipped, and the edge annotated
+// with the model's reasoning.  Relationships with an ambiguous polarity
+// are left alone.
+func (d diagrammer) VerifyPolarities(ctx context.Context, m *Map) (*Map, error) {
+	responseSchema, err := json.MarshalIndent(polarityVerdictsSchema, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	opts := []chat.Option{
+		chat.WithResponseFormat("polarity_verdicts", true, polarityVerdictsSchema),
+		chat.WithSystemPrompt(strings.ReplaceAll(verifyPrompt, "{schema}", string(responseSchema))),
+	}
+	opts = append(opts, d.samplingOptions()...)
+
+	// each distinct signed edge is verified once
+	var edges []edgeKey
+	reasoning := make(map[edgeKey]string)
+	for _, r := range m.Relationships() {
+		if isAmbiguousPolarity(r.Polarity) {
+			continue
+		}
+		k := edgeKey{from: normalizeVariable(r.From), to: normalizeVariable(r.To), polarity: r.Polarity}
+		if _, ok := reasoning[k]; !ok {
+			edges = append(edges, k)
+		}
+		if reasoning[k] == "" {
+			reasoning[k] = cmp.Or(r.PolarityReasoning, r.Reasoning)
+		}
+	}
+
+	verified := &Map{
+		Title:        m.Title,
+		Explanation:  m.Explanation,
+		CausalChains: cloneChains(m.CausalChains),
+		Annotations:  append([]Annotation(nil), m.Annotations...),
+	}
+	names := m.displayNames()
+
+	flips := make(map[edgeKey]string)
+	for start := 0; start < len(edges); start += verifyBatchSize {
+		batch := edges[start:min(start+verifyBatchSize, len(edges))]
+
+		var b strings.Builder
+		for i, e := range batch {
+			fmt.Fprintf(&b, "e%d: %s →(%s) %s", i+1, names[e.from], e.polarity, names[e.to])
+			if reason := reasoning[e]; reason != "" {
+				fmt.Fprintf(&b, " — %s", reason)
+			}
+			b.WriteByte('\n')
+		}
+
+		content, _, err := d.completion(ctx, []chat.Message{{Role: chat.UserRole, Content: b.String()}}, opts)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Verdicts []polarityVerdict `json:"verdicts"`
+		}
+		if err := json.Unmarshal([]byte(content), &response); err != nil {
+			d.metrics.IncFailure(chat.FailureParse)
+			return nil, fmt.Errorf("json.Unmarshal: %w", err)
+		}
+
+		for _, v := range response.Verdicts {
+			var i int
+			if _, err := fmt.Sscanf(v.ID, "e%d", &i); err != nil || i < 1 || i > len(batch) {
+				continue
+			}
+			e := batch[i-1]
+			if v.Polarity != e.polarity && !isAmbiguousPolarity(v.Polarity) {
+				flips[e] = v.Polarity
+				verified.AnnotateEdge(e.from, e.to, fmt.Sprintf("polarity changed from %s to %s on review: %s", e.polarity, v.Polarity, v.Reasoning))
+			}
+		}
+	}
+
+	for _, c := range verified.CausalChains {
+		from := c.InitialVariable
+		for i, r := range c.Relationships {
+			k := edgeKey{from: normalizeVariable(from), to: normalizeVariable(r.Variable), polarity: r.Polarity}
+			if polarity, ok := flips[k]; ok {
+				c.Relationships[i].Polarity = polarity
+			}
+			from = r.Variable
+		}
+	}
+
+	return verified, nil
+}
+
+// Words in polarity reasoning that describe a quantity going up or down.
+// Short words must match exactly; the rest are stems matched as
+// prefixes.
+var (
+	increaseWords = []string{"more", "higher", "greater"}
+	decreaseWords = []string{"less", "fewer", "lower", "smaller"}
+	increaseStems = []string{"increas", "rais", "rise", "rising", "rose", "grow", "grew", "boost", "strengthen", "intensif"}
+	decreaseStems = []string{"decreas", "reduc", "declin", "diminish", "lessen", "weaken", "shrink", "drop", "fall", "fell"}
+)
+
+// PolarityMismatches flags relationships whose polarity reasoning reads
+// as the opposite of their polarity, a common model self-inconsistency:
+// "more taxes reduce trade" on a positive link.  Each word for a
+// decrease in the reasoning flips the sign it implies, so "an increase in
+// A increases B" implies a positive link and "A reduces B" a negative
+// one.  Reasoning without any such words, and links of unknown polarity,
+// aren't flagged.  It is a heuristic; VerifyPolarities asks the model.
+func (m *Map) PolarityMismatches() []Relationship {
+	var mismatches []Relationship
+	for _, r := range m.Relationships() {
+		if r.Polarity != "+" && r.Polarity != "-" {
+			continue
+		}
+		if implied, ok := impliedPolarity(r.PolarityReasoning); ok && implied != r.Polarity {
+			mismatches = append(mismatches, r)
+		}
+	}
+	return mismatches
+}
+
+// impliedPolarity is the polarity the wording of reasoning implies, if it
+// mentions any change in direction at all.
+func impliedPolarity(reasoning string) (string, bool) {
+	hasPrefix := func(word string, stems []string) bool {
+		return slices.ContainsFunc(stems, func(stem string) bool {
+			return strings.HasPrefix(word, stem)
+		})
+	}
+
+	var directional, decreases int
+	for _, word := range strings.FieldsFunc(strings.ToLower(reasoning), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		switch {
+		case slices.Contains(increaseWords, word) || hasPrefix(word, increaseStems):
+			directional++
+		case slices.Contains(decreaseWords, word) || hasPrefix(word, decreaseStems):
+			directional++
+			decreases++
+		}
+	}
+
+	if directional == 0 {
+		return "", false
+	}
+	if decreases%2 == 1 {
+		return "-", true
+	}
+	return "+", true
+}
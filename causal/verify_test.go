@@ -0,0 +1,66 @@
+package causal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPolarities(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Tax Burden", To: "Tensions", Polarity: "+", PolarityReasoning: "Higher taxes raised tensions."},
+		{From: "Tensions", To: "Clashes", Polarity: "-", PolarityReasoning: "Rising tensions made clashes more likely."},
+		{From: "Clashes", To: "Tensions", Polarity: "?"},
+	})
+	client := &mockClient{contents: []string{`{"verdicts": [
+		{"id": "e1", "polarity": "+", "reasoning": "Taxes raise tensions."},
+		{"id": "e2", "polarity": "+", "reasoning": "More tension means more clashes."}
+	]}`}}
+
+	verified, err := NewDiagrammer(client).VerifyPolarities(context.Background(), m)
+	require.NoError(t, err)
+
+	require.Len(t, client.calls, 1)
+	request := client.calls[0][0].Content
+	assert.Contains(t, request, "e1: Tax Burden →(+) Tensions — Higher taxes raised tensions.")
+	assert.Contains(t, request, "e2: Tensions →(-) Clashes")
+	assert.NotContains(t, request, "e3")
+	assert.NotNil(t, client.options[0].ResponseFormat)
+
+	polarities := make(map[[2]string]string)
+	for _, r := range verified.Relationships() {
+		polarities[[2]string{r.From, r.To}] = r.Polarity
+	}
+	assert.Equal(t, map[[2]string]string{
+		{"Tax Burden", "Tensions"}: "+",
+		{"Tensions", "Clashes"}:    "+",
+		{"Clashes", "Tensions"}:    "?",
+	}, polarities)
+	assert.Equal(t, []string{"polarity changed from - to + on review: More tension means more clashes."}, verified.EdgeNotes("Tensions", "Clashes"))
+	assert.Empty(t, verified.EdgeNotes("Tax Burden", "Tensions"))
+
+	// the input map is untouched
+	assert.Equal(t, "-", m.Relationships()[1].Polarity)
+}
+
+func TestPolarityMismatches(t *testing.T) {
+	m := NewMap([]Relationship{
+		{From: "Boycotts", To: "Trade", Polarity: "+", PolarityReasoning: "Boycotts sharply reduce trade with Britain."},
+		{From: "Tax Burden", To: "Tensions", Polarity: "+", PolarityReasoning: "Higher taxes raised tensions."},
+		{From: "Trade", To: "Poverty", Polarity: "-", PolarityReasoning: "Less trade leads to more poverty."},
+		{From: "Tensions", To: "Clashes", Polarity: "-", PolarityReasoning: "Rising tensions made clashes more likely."},
+		{From: "Clashes", To: "Tensions", Polarity: "+", PolarityReasoning: "Clashes make colonists angry."},
+		{From: "Tensions", To: "Boycotts", Polarity: "?", PolarityReasoning: "Tensions reduce boycotts."},
+	})
+
+	mismatches := m.PolarityMismatches()
+	require.Len(t, mismatches, 2)
+	assert.Equal(t, "Boycotts", mismatches[0].From)
+	assert.Equal(t, "Trade", mismatches[0].To)
+	assert.Equal(t, "Tensions", mismatches[1].From)
+	assert.Equal(t, "Clashes", mismatches[1].To)
+
+	assert.Empty(t, testMap1.PolarityMismatches())
+}
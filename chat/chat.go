@@ -2,13 +2,19 @@ package chat
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"path"
 
 	"github.com/isee-systems/sd-ai/schema"
 )
 
 type requestOpts struct {
 	temperature     *float64
+	topP            *float64
+	seed            *int
+	stop            []string
 	reasoningEffort string
 	responseFormat  *JsonSchema
 	maxTokens       int
@@ -17,6 +23,9 @@ type requestOpts struct {
 
 type Options struct {
 	Temperature     *float64
+	TopP            *float64
+	Seed            *int
+	Stop            []string
 	ReasoningEffort string
 	ResponseFormat  *JsonSchema
 	MaxTokens       int
@@ -37,6 +46,26 @@ func WithTemperature(t float64) Option {
 	}
 }
 
+func WithTopP(p float64) Option {
+	return func(opts *requestOpts) {
+		opts.topP = &p
+	}
+}
+
+// WithSeed asks the provider to sample deterministically.  Not every
+// provider honors it, and those that do only make a best effort.
+func WithSeed(seed int) Option {
+	return func(opts *requestOpts) {
+		opts.seed = &seed
+	}
+}
+
+func WithStop(sequences []string) Option {
+	return func(opts *requestOpts) {
+		opts.stop = sequences
+	}
+}
+
 func WithReasoningEffort(lowMedHigh string) Option {
 	return func(opts *requestOpts) {
 		opts.reasoningEffort = lowMedHigh
@@ -65,6 +94,40 @@ func WithSystemPrompt(prompt string) Option {
 	}
 }
 
+// Profile is a reusable bundle of request settings, typically tuned for a
+// particular model.  Zero fields leave the corresponding setting alone.
+type Profile struct {
+	// Name identifies the profile, e.g. by the model it is tuned for.
+	Name            string
+	Temperature     *float64
+	TopP            *float64
+	Seed            *int
+	ReasoningEffort string
+	MaxTokens       int
+}
+
+// WithProfile applies the profile's settings, overriding any given
+// earlier.
+func WithProfile(p Profile) Option {
+	return func(opts *requestOpts) {
+		if p.Temperature != nil {
+			opts.temperature = p.Temperature
+		}
+		if p.TopP != nil {
+			opts.topP = p.TopP
+		}
+		if p.Seed != nil {
+			opts.seed = p.Seed
+		}
+		if p.ReasoningEffort != "" {
+			opts.reasoningEffort = p.ReasoningEffort
+		}
+		if p.MaxTokens != 0 {
+			opts.maxTokens = p.MaxTokens
+		}
+	}
+}
+
 func ApplyOptions(opts ...Option) Options {
 	var options requestOpts
 	for _, opt := range opts {
@@ -73,6 +136,9 @@ func ApplyOptions(opts ...Option) Options {
 
 	return Options{
 		Temperature:     options.temperature,
+		TopP:            options.topP,
+		Seed:            options.seed,
+		Stop:            options.stop,
 		ReasoningEffort: options.reasoningEffort,
 		ResponseFormat:  options.responseFormat,
 		MaxTokens:       options.maxTokens,
@@ -81,10 +147,14 @@ func ApplyOptions(opts ...Option) Options {
 }
 
 const (
-	UserRole   = "user"
-	SystemRole = "system"
+	UserRole      = "user"
+	SystemRole    = "system"
+	AssistantRole = "assistant"
 )
 
+// Client sends chat completion requests to a model.  Implementations must
+// be safe for concurrent use, as one client is typically shared by every
+// request a server handles.
 type Client interface {
 	ChatCompletion(ctx context.Context, msgs []Message, opts ...Option) (io.Reader, error)
 }
@@ -101,5 +171,42 @@ func WithDebugDir(ctx context.Context, dir string) context.Context {
 }
 
 func DebugDir(ctx context.Context) string {
-	return ctx.Value(debugDirContextKey{}).(string)
+	dir, _ := ctx.Value(debugDirContextKey{}).(string)
+	return dir
+}
+
+// DebugSink receives the artifacts (like request.json and response.json)
+// clients record for debugging.
+type DebugSink interface {
+	WriteArtifact(name string, data []byte) error
+}
+
+// dirSink writes each artifact to a file in the directory.
+type dirSink string
+
+func (dir dirSink) WriteArtifact(name string, data []byte) error {
+	outputPath := path.Join(string(dir), name)
+	if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+		return fmt.Errorf("os.WriteFile(%s): %w", outputPath, err)
+	}
+	return nil
+}
+
+type debugSinkContextKey struct{}
+
+func WithDebugSink(ctx context.Context, sink DebugSink) context.Context {
+	return context.WithValue(ctx, debugSinkContextKey{}, sink)
+}
+
+// DebugSinkFrom returns the sink set with WithDebugSink or, failing that,
+// one writing to the directory set with WithDebugDir.  It returns nil if
+// neither was set.
+func DebugSinkFrom(ctx context.Context) DebugSink {
+	if sink, ok := ctx.Value(debugSinkContextKey{}).(DebugSink); ok {
+		return sink
+	}
+	if dir := DebugDir(ctx); dir != "" {
+		return dirSink(dir)
+	}
+	return nil
 }
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"time"
+)
+
+// FailureKind classifies why a generation failed.
+type FailureKind string
+
+const (
+	// FailureHTTP is a failure to get a response from the provider.
+	FailureHTTP FailureKind = "http"
+	// FailureParse is a response that couldn't be decoded.
+	FailureParse FailureKind = "parse"
+	// FailureValidation is a response that didn't satisfy the requested
+	// constraints.
+	FailureValidation FailureKind = "validation"
+	// FailureRefusal is a model declining to respond at all.
+	FailureRefusal FailureKind = "refusal"
+)
+
+// Metrics receives events from diagrammers and clients, for exporting to
+// a monitoring system.  Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveGeneration is called once per generation, successful or
+	// not, with how long it took.
+	ObserveGeneration(latency time.Duration)
+	// IncFailure counts a failure of the given kind.
+	IncFailure(kind FailureKind)
+	// IncRetry counts a request re-sent after a retryable error.
+	IncRetry()
+}
+
+type noMetrics struct{}
+
+func (noMetrics) ObserveGeneration(time.Duration) {}
+func (noMetrics) IncFailure(FailureKind)          {}
+func (noMetrics) IncRetry()                       {}
+
+// NoMetrics discards all events; it is the default.
+var NoMetrics Metrics = noMetrics{}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Response is returned by clients that talk to a provider over HTTP.  It
+// reads like the plain response body, but also carries the status and
+// headers of the successful response.
+type Response struct {
+	io.Reader
+	StatusCode int
+	Header     http.Header
+	RateLimit  RateLimitInfo
+}
+
+// RateLimitInfo is the provider's view of our remaining budget, as
+// reported in the `x-ratelimit-*` response headers.  Fields are zero when
+// the provider didn't send the corresponding header.
+type RateLimitInfo struct {
+	LimitRequests     int
+	LimitTokens       int
+	RemainingRequests int
+	RemainingTokens   int
+	ResetRequests     time.Duration
+	ResetTokens       time.Duration
+}
+
+// ParseRateLimitInfo reads the `x-ratelimit-*` headers OpenAI-compatible
+// providers send.  Missing or malformed headers leave their field zero;
+// reset times are Go durations like "6m0s" or "1.5s".
+func ParseRateLimitInfo(h http.Header) RateLimitInfo {
+	atoi := func(key string) int {
+		n, _ := strconv.Atoi(h.Get(key))
+		return n
+	}
+	duration := func(key string) time.Duration {
+		d, _ := time.ParseDuration(h.Get(key))
+		return d
+	}
+
+	return RateLimitInfo{
+		LimitRequests:     atoi("x-ratelimit-limit-requests"),
+		LimitTokens:       atoi("x-ratelimit-limit-tokens"),
+		RemainingRequests: atoi("x-ratelimit-remaining-requests"),
+		RemainingTokens:   atoi("x-ratelimit-remaining-tokens"),
+		ResetRequests:     duration("x-ratelimit-reset-requests"),
+		ResetTokens:       duration("x-ratelimit-reset-tokens"),
+	}
+}
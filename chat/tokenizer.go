@@ -0,0 +1,22 @@
+package chat
+
+// Tokenizer counts the tokens text takes up in a particular model's
+// context window.  Exact counts need the model's own tokenizer; a
+// tiktoken-compatible implementation can be adapted with TokenizerFunc.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// HeuristicTokenizer is a rough, tokenizer-free estimate, for when the
+// model's tokenizer isn't available: about 4 characters per token, as is
+// typical for English text.
+var HeuristicTokenizer Tokenizer = TokenizerFunc(func(text string) int {
+	return (len(text) + 3) / 4
+})
@@ -0,0 +1,269 @@
+// Package conformance runs a set of prompts against a set of models and
+// saves what each produced, for browsing and comparing the results.
+package conformance
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isee-systems/sd-ai/causal"
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/openai"
+)
+
+// SummaryFile is the name of the CSV, in the results directory, that Run
+// appends a row to for each model and case.
+const SummaryFile = "summary.csv"
+
+var summaryHeader = []string{"model", "case", "status", "variables", "relationships", "loops", "seconds", "error"}
+
+// Case is a prompt to generate a map for.
+type Case struct {
+	Name                string
+	Prompt              string
+	BackgroundKnowledge string
+}
+
+// Result is the outcome of running one case against one model.
+type Result struct {
+	Model string
+	Case  string
+	// Dir holds the case's result.json and diagram.svg, along with the
+	// request and response artifacts; it is empty if the model was
+	// skipped.
+	Dir      string
+	Skipped  bool
+	Map      *causal.Map
+	Duration time.Duration
+	Err      error
+}
+
+type runner struct {
+	newClient  func(model string) (chat.Client, error)
+	installed  func(ctx context.Context) ([]string, error)
+	render     func(ctx context.Context, m *causal.Map) ([]byte, error)
+	diagrammer []causal.Option
+}
+
+type Option func(*runner)
+
+// WithClient sets how a client is made for each model.  By default it
+// talks to a local Ollama server.
+func WithClient(newClient func(model string) (chat.Client, error)) Option {
+	return func(r *runner) {
+		r.newClient = newClient
+	}
+}
+
+// WithInstalled sets how the installed models are listed; models that
+// aren't listed are skipped.  By default, the local Ollama server is
+// asked.  A nil list function runs every model.
+func WithInstalled(installed func(ctx context.Context) ([]string, error)) Option {
+	return func(r *runner) {
+		r.installed = installed
+	}
+}
+
+// WithRenderer sets how diagram.svg is rendered from each map.  By
+// default it is Map.VisualSVG, which needs Graphviz.
+func WithRenderer(render func(ctx context.Context, m *causal.Map) ([]byte, error)) Option {
+	return func(r *runner) {
+		r.render = render
+	}
+}
+
+// WithDiagrammerOptions are passed to every diagrammer, after the
+// model's profile.
+func WithDiagrammerOptions(opts ...causal.Option) Option {
+	return func(r *runner) {
+		r.diagrammer = append(r.diagrammer, opts...)
+	}
+}
+
+// Run generates a map for each model and case, saving result.json and
+// diagram.svg in dir/<model>/<case>, and appending a row per model and
+// case to dir/summary.csv.  A failure to generate is recorded in the
+// summary and the result rather than stopping the run; the error
+// returned is for failures to list models or write results.
+func Run(ctx context.Context, dir string, models []chat.Profile, cases []Case, opts ...Option) ([]Result, error) {
+	r := &runner{
+		newClient: func(model string) (chat.Client, error) {
+			return openai.NewClient(openai.OllamaURL, model)
+		},
+		installed: func(ctx context.Context) ([]string, error) {
+			return openai.InstalledModels(ctx, openai.OllamaNativeURL)
+		},
+		render: func(ctx context.Context, m *causal.Map) ([]byte, error) {
+			return m.VisualSVG(ctx)
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	var installed []string
+	if r.installed != nil {
+		var err error
+		if installed, err = r.installed(ctx); err != nil {
+			return nil, fmt.Errorf("listing installed models: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	summary, err := openSummary(path.Join(dir, SummaryFile))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = summary.Close() }()
+	w := csv.NewWriter(summary)
+
+	var results []Result
+	for _, model := range models {
+		skip := r.installed != nil && !isInstalled(installed, model.Name)
+		for _, c := range cases {
+			result := Result{Model: model.Name, Case: c.Name, Skipped: skip}
+			if !skip {
+				result.Dir = path.Join(dir, pathComponent(model.Name), pathComponent(c.Name))
+				if err := r.run(ctx, model, c, &result); err != nil {
+					return results, err
+				}
+			}
+			results = append(results, result)
+
+			if err := w.Write(summaryRow(result)); err != nil {
+				return results, fmt.Errorf("writing %s: %w", SummaryFile, err)
+			}
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return results, fmt.Errorf("writing %s: %w", SummaryFile, err)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// run generates the map for a single case, recording the outcome in
+// result.  Only errors writing the results are returned.
+func (r *runner) run(ctx context.Context, model chat.Profile, c Case, result *Result) error {
+	if err := os.RemoveAll(result.Dir); err != nil {
+		return fmt.Errorf("os.RemoveAll: %w", err)
+	}
+	if err := os.MkdirAll(result.Dir, 0o755); err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	client, err := r.newClient(model.Name)
+	if err != nil {
+		result.Err = err
+		return nil
+	}
+	d := causal.NewDiagrammer(client, append([]causal.Option{causal.WithProfile(model)}, r.diagrammer...)...)
+
+	start := time.Now()
+	result.Map, result.Err = d.Generate(chat.WithDebugDir(ctx, result.Dir), c.Prompt, c.BackgroundKnowledge)
+	result.Duration = time.Since(start)
+	if result.Err != nil {
+		return nil
+	}
+
+	resultJSON, err := json.MarshalIndent(result.Map, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	if err := os.WriteFile(path.Join(result.Dir, "result.json"), resultJSON, 0o644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+
+	svg, err := r.render(ctx, result.Map)
+	if err != nil {
+		// the map is still worth having without its diagram
+		result.Err = fmt.Errorf("rendering diagram: %w", err)
+		return nil
+	}
+	if err := os.WriteFile(path.Join(result.Dir, "diagram.svg"), svg, 0o644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+// openSummary opens the summary CSV for appending, writing its header if
+// the file is new.
+func openSummary(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("os.OpenFile: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("f.Stat: %w", err)
+	}
+	if info.Size() == 0 {
+		w := csv.NewWriter(f)
+		_ = w.Write(summaryHeader)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("writing %s: %w", SummaryFile, err)
+		}
+	}
+	return f, nil
+}
+
+func summaryRow(result Result) []string {
+	status := "ok"
+	switch {
+	case result.Skipped:
+		status = "skipped"
+	case result.Err != nil:
+		status = "error"
+	}
+
+	var variables, relationships, loops, errText string
+	if result.Map != nil {
+		variables = strconv.Itoa(result.Map.VariableCount())
+		relationships = strconv.Itoa(len(result.Map.Relationships()))
+		loops = strconv.Itoa(len(result.Map.Loops()))
+	}
+	if result.Err != nil {
+		errText = result.Err.Error()
+	}
+
+	return []string{
+		result.Model,
+		result.Case,
+		status,
+		variables,
+		relationships,
+		loops,
+		strconv.FormatFloat(result.Duration.Seconds(), 'f', 1, 64),
+		errText,
+	}
+}
+
+// isInstalled reports whether the model is in the installed list.  As
+// with Ollama itself, a name without a tag means the latest.
+func isInstalled(installed []string, model string) bool {
+	if !strings.Contains(model, ":") {
+		model += ":latest"
+	}
+	return slices.Contains(installed, model)
+}
+
+// pathComponent makes a model or case name safe to use as a single
+// directory name.
+func pathComponent(name string) string {
+	return strings.NewReplacer("/", "_", string(os.PathSeparator), "_").Replace(name)
+}
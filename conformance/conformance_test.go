@@ -0,0 +1,100 @@
+package conformance
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/isee-systems/sd-ai/causal"
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/openai"
+)
+
+const mapJSON = `{"title":"Tensions","explanation":"Taxes drive tensions.","causal_chains":[{"initial_variable":"Tax Burden","relationships":[{"variable":"Tensions","polarity":"+","reasoning":"taxes anger colonists","polarity_reasoning":"more taxes, more anger"},{"variable":"Tax Burden","polarity":"+","reasoning":"unrest brings enforcement","polarity_reasoning":"more unrest, more taxes"}]}]}`
+
+// staticClient always responds with the same content.
+type staticClient string
+
+func (c staticClient) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ...chat.Option) (io.Reader, error) {
+	var ccr openai.ChatCompletionResponse
+	ccr.Choices = make([]openai.ChatCompletionChoice, 1)
+	ccr.Choices[0].Message.Role = chat.AssistantRole
+	ccr.Choices[0].Message.Content = string(c)
+
+	body, err := json.Marshal(ccr)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(string(body)), nil
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	models := []chat.Profile{{Name: "good"}, {Name: "org/bad:7b"}, {Name: "missing"}}
+	cases := []Case{{Name: "Revolution", Prompt: "explain the revolution", BackgroundKnowledge: "taxes caused tension."}}
+
+	results, err := Run(context.Background(), dir, models, cases,
+		WithClient(func(model string) (chat.Client, error) {
+			if model == "good" {
+				return staticClient(mapJSON), nil
+			}
+			return nil, errors.New("no such model")
+		}),
+		WithInstalled(func(ctx context.Context) ([]string, error) {
+			return []string{"good:latest", "org/bad:7b"}, nil
+		}),
+		WithRenderer(func(ctx context.Context, m *causal.Map) ([]byte, error) {
+			return []byte("<svg/>"), nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	good := results[0]
+	require.NoError(t, good.Err)
+	assert.Equal(t, path.Join(dir, "good", "Revolution"), good.Dir)
+	assert.FileExists(t, path.Join(good.Dir, "result.json"))
+	svg, err := os.ReadFile(path.Join(good.Dir, "diagram.svg"))
+	require.NoError(t, err)
+	assert.Equal(t, "<svg/>", string(svg))
+
+	bad := results[1]
+	assert.Error(t, bad.Err)
+	assert.Equal(t, path.Join(dir, "org_bad:7b", "Revolution"), bad.Dir)
+	assert.NoFileExists(t, path.Join(bad.Dir, "result.json"))
+
+	assert.True(t, results[2].Skipped)
+	assert.NoDirExists(t, path.Join(dir, "missing"))
+
+	// a second run appends to the summary rather than replacing it
+	_, err = Run(context.Background(), dir, models[:1], cases,
+		WithClient(func(string) (chat.Client, error) { return staticClient(mapJSON), nil }),
+		WithInstalled(nil),
+		WithRenderer(func(context.Context, *causal.Map) ([]byte, error) { return []byte("<svg/>"), nil }),
+	)
+	require.NoError(t, err)
+
+	f, err := os.Open(path.Join(dir, SummaryFile))
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	rows, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, rows, 5)
+	assert.Equal(t, summaryHeader, rows[0])
+	assert.Equal(t, []string{"good", "Revolution", "ok", "2", "2", "1"}, rows[1][:6])
+	assert.Equal(t, []string{"org/bad:7b", "Revolution", "error"}, rows[2][:3])
+	assert.Equal(t, "no such model", rows[2][7])
+	assert.Equal(t, []string{"missing", "Revolution", "skipped"}, rows[3][:3])
+	assert.Equal(t, "ok", rows[4][2])
+}
@@ -166,12 +166,14 @@ var specificConstraints = map[string]conformanceConstraints{
 	},
 }
 
-var llmModels = []string{
-	//"gemma3:27b",
-	//"gemma2",
-	//"phi4",
-	"llama3.3:70b-instruct-q4_K_M",
-	//"qwq",
+// llmModels are the models the suite runs against, each named by its
+// profile.
+var llmModels = []chat.Profile{
+	//{Name: "gemma3:27b"},
+	//{Name: "gemma2"},
+	//{Name: "phi4"},
+	{Name: "llama3.3:70b-instruct-q4_K_M"},
+	//{Name: "qwq", ReasoningEffort: "medium"},
 }
 
 func TestConformance(t *testing.T) {
@@ -194,12 +196,12 @@ func TestConformance(t *testing.T) {
 
 	for _, llm := range llmModels {
 		for _, testCase := range allTests {
-			name := fmt.Sprintf("%s_(%s):_%s", llm, testCase.name, testCase.conformance.additionalPrompt)
+			name := fmt.Sprintf("%s_(%s):_%s", llm.Name, testCase.name, testCase.conformance.additionalPrompt)
 			t.Run(name, func(t *testing.T) {
-				c, err := openai.NewClient(openai.OllamaURL, llm)
+				c, err := openai.NewClient(openai.OllamaURL, llm.Name)
 				require.NoError(t, err)
 
-				d := causal.NewDiagrammer(c)
+				d := causal.NewDiagrammer(c, causal.WithProfile(llm), causal.WithDeterminism())
 
 				prompt := testCase.prompt + "\n\n" + testCase.conformance.additionalPrompt
 
@@ -230,10 +232,10 @@ func TestConformance(t *testing.T) {
 				}
 
 				if requirements.minVariables > 0 {
-					assert.GreaterOrEqualf(t, len(vars), int(requirements.minVariables), "expected at least %d variables, got %v", requirements.minVariables, vars.Slice())
+					assert.GreaterOrEqualf(t, result.VariableCount(), int(requirements.minVariables), "expected at least %d variables, got %v", requirements.minVariables, vars.Slice())
 				}
 				if requirements.maxVariables > 0 {
-					assert.LessOrEqualf(t, len(vars), int(requirements.maxVariables), "expected at most %d variables, got %v", requirements.maxVariables, vars.Slice())
+					assert.LessOrEqualf(t, result.VariableCount(), int(requirements.maxVariables), "expected at most %d variables, got %v", requirements.maxVariables, vars.Slice())
 				}
 				if requirements.minFeedback > 0 {
 					assert.GreaterOrEqualf(t, len(loops), int(requirements.minFeedback), "expected at least %d loops, got %v", requirements.minFeedback, loops)
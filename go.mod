@@ -2,11 +2,13 @@ module github.com/isee-systems/sd-ai
 
 go 1.24
 
-require github.com/stretchr/testify v1.10.0
+require (
+	github.com/gertd/go-pluralize v0.2.1
+	github.com/stretchr/testify v1.10.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/gertd/go-pluralize v0.2.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
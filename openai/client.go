@@ -2,13 +2,14 @@ package openai
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path"
 	"strings"
+	"time"
 
 	"github.com/isee-systems/sd-ai/chat"
 )
@@ -16,20 +17,120 @@ import (
 const (
 	OpenAIURL = "https://api.openai.com/v1"
 	OllamaURL = "http://localhost:11434/v1"
+	// OllamaNativeURL is the base for Ollama's native API, for use with
+	// WithOllamaNative.
+	OllamaNativeURL = "http://localhost:11434"
 )
 
 type client struct {
-	apiBaseUrl string
-	modelName  string
+	apiBaseUrl   string
+	modelName    string
+	noSystemRole bool
+	ollamaNative bool
+	keepAlive    time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	headers      http.Header
+	metrics      chat.Metrics
 }
 
 var _ chat.Client = &client{}
 
-func NewClient(apiBase, modelName string) (chat.Client, error) {
-	return &client{
+type ClientOption func(*client)
+
+// WithoutSystemRole is for models that ignore messages with the system
+// role: the system prompt is instead prepended to the first user message.
+func WithoutSystemRole() ClientOption {
+	return func(c *client) {
+		c.noSystemRole = true
+	}
+}
+
+// WithOllamaNative talks to Ollama's native /api/chat endpoint rather than
+// its OpenAI-compatible /v1 shim, so apiBase should be OllamaNativeURL.
+// Responses are translated into the OpenAI shape, so callers can't tell
+// the difference.
+func WithOllamaNative() ClientOption {
+	return func(c *client) {
+		c.ollamaNative = true
+	}
+}
+
+// WithKeepAlive sets how long Ollama keeps the model loaded after a
+// request.  It only has an effect in native Ollama mode.
+func WithKeepAlive(d time.Duration) ClientOption {
+	return func(c *client) {
+		c.keepAlive = d
+	}
+}
+
+// WithRetries re-sends requests that fail with a rate-limit or server
+// error up to n more times, waiting backoff before the first retry and
+// doubling the wait each time after.
+func WithRetries(n int, backoff time.Duration) ClientOption {
+	return func(c *client) {
+		c.maxRetries = n
+		c.retryBackoff = backoff
+	}
+}
+
+// WithMetrics counts the client's retries.
+func WithMetrics(m chat.Metrics) ClientOption {
+	return func(c *client) {
+		c.metrics = m
+	}
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *client) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(key, value)
+	}
+}
+
+// WithOrganization scopes requests to an OpenAI organization.
+func WithOrganization(org string) ClientOption {
+	return WithHeader("OpenAI-Organization", org)
+}
+
+// WithProject scopes requests to an OpenAI project.
+func WithProject(project string) ClientOption {
+	return WithHeader("OpenAI-Project", project)
+}
+
+// NewClient returns a client for the OpenAI-compatible API at apiBase.
+// The client holds no per-request state and sends requests with
+// http.DefaultClient, so it is safe for concurrent use.
+func NewClient(apiBase, modelName string, opts ...ClientOption) (chat.Client, error) {
+	c := &client{
 		apiBaseUrl: apiBase,
 		modelName:  modelName,
-	}, nil
+		metrics:    chat.NoMetrics,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// foldSystemPrompt prepends the system prompt to the first user message,
+// or adds it as a user message of its own if there isn't one.
+func foldSystemPrompt(systemPrompt string, msgs []chat.Message) []chat.Message {
+	folded := make([]chat.Message, 0, len(msgs)+1)
+	folded = append(folded, msgs...)
+
+	for i, msg := range folded {
+		if msg.Role == chat.UserRole {
+			folded[i].Content = systemPrompt + "\n\n" + msg.Content
+			return folded
+		}
+	}
+
+	return append([]chat.Message{{Role: chat.UserRole, Content: systemPrompt}}, msgs...)
 }
 
 type responseFormat struct {
@@ -42,6 +143,9 @@ type chatCompletionRequest struct {
 	Model           string          `json:"model,omitempty"`
 	ResponseFormat  *responseFormat `json:"response_format,omitempty"`
 	Temperature     *float64        `json:"temperature,omitempty"`
+	TopP            *float64        `json:"top_p,omitempty"`
+	Seed            *int            `json:"seed,omitempty"`
+	Stop            []string        `json:"stop,omitempty"`
 	ReasoningEffort string          `json:"reasoning_effort,omitempty"`
 	MaxTokens       int             `json:"max_tokens,omitempty"`
 }
@@ -50,7 +154,9 @@ func (c client) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ..
 	reqOpts := chat.ApplyOptions(opts...)
 
 	// for OpenAI models, the system prompt is the first message in the list of messages
-	if reqOpts.SystemPrompt != "" {
+	if reqOpts.SystemPrompt != "" && c.noSystemRole {
+		msgs = foldSystemPrompt(reqOpts.SystemPrompt, msgs)
+	} else if reqOpts.SystemPrompt != "" {
 		allMsgs := make([]chat.Message, 0, len(msgs)+1)
 		allMsgs = append(allMsgs, chat.Message{
 			Role:    chat.SystemRole,
@@ -60,11 +166,19 @@ func (c client) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ..
 		msgs = allMsgs
 	}
 
+	if c.ollamaNative {
+		return c.ollamaChatCompletion(ctx, msgs, reqOpts)
+	}
+
 	req := &chatCompletionRequest{
 		Messages:        msgs,
 		Model:           c.modelName,
 		Temperature:     reqOpts.Temperature,
+		TopP:            reqOpts.TopP,
+		Seed:            reqOpts.Seed,
+		Stop:            reqOpts.Stop,
 		ReasoningEffort: reqOpts.ReasoningEffort,
+		MaxTokens:       reqOpts.MaxTokens,
 	}
 
 	if reqOpts.ResponseFormat != nil {
@@ -78,31 +192,64 @@ func (c client) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ..
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal: %w", err)
 	}
-	body := strings.NewReader(string(bodyBytes))
 
-	if debugDir := chat.DebugDir(ctx); debugDir != "" {
-		outputPath := path.Join(debugDir, "request.json")
-		if err = os.WriteFile(outputPath, bodyBytes, 0o644); err != nil {
-			return nil, fmt.Errorf("os.WriteFile(%s): %w", outputPath, err)
+	return c.post(ctx, "/chat/completions", bodyBytes)
+}
+
+// post sends the request body to the given endpoint under the API base,
+// recording both the request and response in the debug dir, if any.
+func (c client) post(ctx context.Context, endpoint string, bodyBytes []byte) (*chat.Response, error) {
+	debugSink := chat.DebugSinkFrom(ctx)
+	if debugSink != nil {
+		if err := debugSink.WriteArtifact("request.json", bodyBytes); err != nil {
+			return nil, err
 		}
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPost, c.apiBaseUrl+"/chat/completions", body)
+	// the same key is sent with every attempt, so the provider can tell
+	// that retries are the same logical request
+	idempotencyKey, err := newIdempotencyKey()
 	if err != nil {
-		return nil, fmt.Errorf("http.NewRequest: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiBaseUrl+endpoint, strings.NewReader(string(bodyBytes)))
+		if err != nil {
+			return nil, fmt.Errorf("http.NewRequest: %w", err)
+		}
 
-	resp, err := http.DefaultClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("http.DefaultClient.Do: %w", err)
-	}
+		for key, values := range c.headers {
+			for _, value := range values {
+				httpReq.Header.Add(key, value)
+			}
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+
+		resp, err = http.DefaultClient.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("http.DefaultClient.Do: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
 
-	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if !retryable(resp.StatusCode) || attempt >= c.maxRetries {
+			return nil, fmt.Errorf("http status code: %d (%s)", resp.StatusCode, string(body))
+		}
 
-		return nil, fmt.Errorf("http status code: %d (%s)", resp.StatusCode, string(body))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.retryBackoff << attempt):
+		}
+		c.metrics.IncRetry()
 	}
 
 	defer func() { _ = resp.Body.Close() }()
@@ -112,14 +259,32 @@ func (c client) ChatCompletion(ctx context.Context, msgs []chat.Message, opts ..
 		return nil, fmt.Errorf("io.ReadAll(resp.Body): %w", err)
 	}
 
-	if debugDir := chat.DebugDir(ctx); debugDir != "" {
-		outputPath := path.Join(debugDir, "response.json")
-		if err = os.WriteFile(outputPath, bodyBytes, 0o644); err != nil {
-			return nil, fmt.Errorf("os.WriteFile(%s): %w", outputPath, err)
+	if debugSink != nil {
+		if err = debugSink.WriteArtifact("response.json", bodyBytes); err != nil {
+			return nil, err
 		}
 	}
 
-	return strings.NewReader(string(bodyBytes)), nil
+	return &chat.Response{
+		Reader:     strings.NewReader(string(bodyBytes)),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		RateLimit:  chat.ParseRateLimitInfo(resp.Header),
+	}, nil
+}
+
+// retryable reports whether a request that failed with the given status
+// might succeed if sent again.
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func newIdempotencyKey() (string, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+	return hex.EncodeToString(key[:]), nil
 }
 
 type ChatCompletionChoice struct {
@@ -127,9 +292,22 @@ type ChatCompletionChoice struct {
 	Message struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
+		// Refusal is set instead of Content when the model declines to
+		// respond.
+		Refusal string `json:"refusal,omitempty"`
 	} `json:"message"`
 }
 
+// ErrModelRefused is returned when the model declines to respond, and
+// carries its explanation of why.
+type ErrModelRefused struct {
+	Refusal string
+}
+
+func (e *ErrModelRefused) Error() string {
+	return fmt.Sprintf("model refused: %s", e.Refusal)
+}
+
 type ChatCompletionResponse struct {
 	Id      string                 `json:"id"`
 	Object  string                 `json:"object"`
@@ -0,0 +1,280 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+const okResponse = `{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"{}"}}]}`
+
+func TestRateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-limit-tokens", "30000")
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		w.Header().Set("x-ratelimit-remaining-tokens", "29000")
+		w.Header().Set("x-ratelimit-reset-requests", "120ms")
+		w.Header().Set("x-ratelimit-reset-tokens", "2s")
+		_, _ = io.WriteString(w, okResponse)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+
+	r, err := c.ChatCompletion(context.Background(), []chat.Message{{Role: chat.UserRole, Content: "hi"}})
+	require.NoError(t, err)
+
+	resp, ok := r.(*chat.Response)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, chat.RateLimitInfo{
+		LimitRequests:     500,
+		LimitTokens:       30000,
+		RemainingRequests: 499,
+		RemainingTokens:   29000,
+		ResetRequests:     120 * time.Millisecond,
+		ResetTokens:       2 * time.Second,
+	}, resp.RateLimit)
+
+	body, err := io.ReadAll(resp)
+	require.NoError(t, err)
+	assert.Equal(t, okResponse, string(body))
+}
+
+// captureRequests starts a server that records the decoded body of each
+// request and responds with an empty successful completion.
+func captureRequests(t *testing.T) (*httptest.Server, *[]map[string]any) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		_, _ = io.WriteString(w, okResponse)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestTopPAndStop(t *testing.T) {
+	srv, bodies := captureRequests(t)
+
+	c, err := NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+
+	msgs := []chat.Message{{Role: chat.UserRole, Content: "hi"}}
+
+	_, err = c.ChatCompletion(context.Background(), msgs, chat.WithTopP(0.9), chat.WithStop([]string{"\n\n", "END"}))
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs)
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 2)
+	set, unset := (*bodies)[0], (*bodies)[1]
+
+	assert.Equal(t, 0.9, set["top_p"])
+	assert.Equal(t, []any{"\n\n", "END"}, set["stop"])
+	assert.NotContains(t, unset, "top_p")
+	assert.NotContains(t, unset, "stop")
+}
+
+func TestMaxTokens(t *testing.T) {
+	srv, bodies := captureRequests(t)
+
+	c, err := NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+
+	msgs := []chat.Message{{Role: chat.UserRole, Content: "hi"}}
+
+	_, err = c.ChatCompletion(context.Background(), msgs, chat.WithMaxTokens(100))
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs)
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 2)
+	assert.Equal(t, 100.0, (*bodies)[0]["max_tokens"])
+	assert.NotContains(t, (*bodies)[1], "max_tokens")
+}
+
+func TestWithoutSystemRole(t *testing.T) {
+	srv, bodies := captureRequests(t)
+
+	msgs := []chat.Message{{Role: chat.UserRole, Content: "hi"}}
+
+	c, err := NewClient(srv.URL, "test-model", WithoutSystemRole())
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs, chat.WithSystemPrompt("be terse"))
+	require.NoError(t, err)
+
+	c, err = NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs, chat.WithSystemPrompt("be terse"))
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 2)
+	folded, separate := (*bodies)[0], (*bodies)[1]
+
+	assert.Equal(t, []any{
+		map[string]any{"role": "user", "content": "be terse\n\nhi"},
+	}, folded["messages"])
+	assert.Equal(t, []any{
+		map[string]any{"role": "system", "content": "be terse"},
+		map[string]any{"role": "user", "content": "hi"},
+	}, separate["messages"])
+	assert.Equal(t, "hi", msgs[0].Content)
+}
+
+func TestOllamaNative(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = io.WriteString(w, `{"model":"test-model","created_at":"2024-01-01T00:00:00Z","message":{"role":"assistant","content":"{\"title\":\"t\"}"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "test-model", WithOllamaNative(), WithKeepAlive(5*time.Minute))
+	require.NoError(t, err)
+
+	s := &schema.JSON{Type: schema.Object}
+	r, err := c.ChatCompletion(context.Background(),
+		[]chat.Message{{Role: chat.UserRole, Content: "hi"}},
+		chat.WithResponseFormat("test", true, s),
+		chat.WithTemperature(0),
+		chat.WithMaxTokens(100),
+		chat.WithSystemPrompt("be terse"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "test-model", body["model"])
+	assert.Equal(t, false, body["stream"])
+	assert.Equal(t, "5m0s", body["keep_alive"])
+	assert.Equal(t, map[string]any{"type": "object"}, body["format"])
+	assert.Equal(t, map[string]any{"temperature": 0.0, "num_predict": 100.0}, body["options"])
+	assert.Equal(t, []any{
+		map[string]any{"role": "system", "content": "be terse"},
+		map[string]any{"role": "user", "content": "hi"},
+	}, body["messages"])
+	assert.NotContains(t, body, "response_format")
+
+	var ccr ChatCompletionResponse
+	require.NoError(t, json.NewDecoder(r).Decode(&ccr))
+	require.Len(t, ccr.Choices, 1)
+	assert.Equal(t, chat.AssistantRole, ccr.Choices[0].Message.Role)
+	assert.Equal(t, `{"title":"t"}`, ccr.Choices[0].Message.Content)
+}
+
+func TestInstalledModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		_, _ = io.WriteString(w, `{"models":[{"name":"llama3:latest"},{"name":"phi4:14b"}]}`)
+	}))
+	defer srv.Close()
+
+	models, err := InstalledModels(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"llama3:latest", "phi4:14b"}, models)
+}
+
+type memorySink map[string][]byte
+
+func (s memorySink) WriteArtifact(name string, data []byte) error {
+	s[name] = data
+	return nil
+}
+
+func TestDebugSink(t *testing.T) {
+	srv, _ := captureRequests(t)
+
+	c, err := NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+
+	sink := make(memorySink)
+	ctx := chat.WithDebugSink(context.Background(), sink)
+	_, err = c.ChatCompletion(ctx, []chat.Message{{Role: chat.UserRole, Content: "hi"}})
+	require.NoError(t, err)
+
+	require.Contains(t, sink, "request.json")
+	assert.Contains(t, string(sink["request.json"]), `"content": "hi"`)
+	assert.Equal(t, okResponse, string(sink["response.json"]))
+}
+
+type retryCounter struct {
+	n int
+}
+
+func (c *retryCounter) ObserveGeneration(time.Duration) {}
+func (c *retryCounter) IncFailure(chat.FailureKind)     {}
+func (c *retryCounter) IncRetry()                       { c.n++ }
+
+func TestRetriesReuseIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys)%3 != 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = io.WriteString(w, okResponse)
+	}))
+	defer srv.Close()
+
+	retries := &retryCounter{}
+	c, err := NewClient(srv.URL, "test-model", WithRetries(2, time.Millisecond), WithMetrics(retries))
+	require.NoError(t, err)
+
+	msgs := []chat.Message{{Role: chat.UserRole, Content: "hi"}}
+	_, err = c.ChatCompletion(context.Background(), msgs)
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs)
+	require.NoError(t, err)
+
+	require.Len(t, keys, 6)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, []string{keys[0], keys[0], keys[0]}, keys[:3])
+	assert.Equal(t, []string{keys[3], keys[3], keys[3]}, keys[3:])
+	assert.NotEqual(t, keys[0], keys[3])
+	assert.Equal(t, 4, retries.n)
+
+	c, err = NewClient(srv.URL, "test-model")
+	require.NoError(t, err)
+	_, err = c.ChatCompletion(context.Background(), msgs)
+	assert.ErrorContains(t, err, "http status code: 429")
+}
+
+func TestHeaders(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_, _ = io.WriteString(w, okResponse)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "test-model",
+		WithOrganization("org-123"),
+		WithProject("proj_456"),
+		WithHeader("X-Trace", "abc"),
+	)
+	require.NoError(t, err)
+
+	_, err = c.ChatCompletion(context.Background(), []chat.Message{{Role: chat.UserRole, Content: "hi"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "org-123", header.Get("OpenAI-Organization"))
+	assert.Equal(t, "proj_456", header.Get("OpenAI-Project"))
+	assert.Equal(t, "abc", header.Get("X-Trace"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+}
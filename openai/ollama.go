@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/isee-systems/sd-ai/chat"
+	"github.com/isee-systems/sd-ai/schema"
+)
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+// ollamaChatRequest is the body of a request to Ollama's native /api/chat.
+// Unlike the /v1 shim, sampling parameters live under options, and format
+// takes the JSON schema directly.
+type ollamaChatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []chat.Message `json:"messages"`
+	Stream    bool           `json:"stream"`
+	Format    *schema.JSON   `json:"format,omitempty"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Model     string       `json:"model"`
+	CreatedAt string       `json:"created_at"`
+	Message   chat.Message `json:"message"`
+	Done      bool         `json:"done"`
+}
+
+func (c client) ollamaChatCompletion(ctx context.Context, msgs []chat.Message, reqOpts chat.Options) (io.Reader, error) {
+	req := &ollamaChatRequest{
+		Model:    c.modelName,
+		Messages: msgs,
+	}
+
+	if reqOpts.ResponseFormat != nil {
+		req.Format = reqOpts.ResponseFormat.Schema
+	}
+	if c.keepAlive != 0 {
+		req.KeepAlive = c.keepAlive.String()
+	}
+	if reqOpts.Temperature != nil || reqOpts.TopP != nil || reqOpts.Seed != nil || len(reqOpts.Stop) > 0 || reqOpts.MaxTokens > 0 {
+		req.Options = &ollamaOptions{
+			Temperature: reqOpts.Temperature,
+			TopP:        reqOpts.TopP,
+			Seed:        reqOpts.Seed,
+			Stop:        reqOpts.Stop,
+			NumPredict:  reqOpts.MaxTokens,
+		}
+	}
+
+	bodyBytes, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	resp, err := c.post(ctx, "/api/chat", bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var ocr ollamaChatResponse
+	if err := json.NewDecoder(resp).Decode(&ocr); err != nil {
+		return nil, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	var ccr ChatCompletionResponse
+	ccr.Object = "chat.completion"
+	ccr.Model = ocr.Model
+	ccr.Choices = make([]ChatCompletionChoice, 1)
+	ccr.Choices[0].Message.Role = ocr.Message.Role
+	ccr.Choices[0].Message.Content = ocr.Message.Content
+
+	bodyBytes, err = json.Marshal(ccr)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	resp.Reader = strings.NewReader(string(bodyBytes))
+	return resp, nil
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// InstalledModels lists the models pulled into the Ollama server at
+// apiBase, which should be OllamaNativeURL.  Names include their tag, as
+// in "llama3:latest".
+func InstalledModels(ctx context.Context, apiBase string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.DefaultClient.Do: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status code: %d", resp.StatusCode)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,113 @@
+// Package prommetrics exports diagrammer and client metrics in the
+// Prometheus text exposition format.  It has no dependencies beyond the
+// standard library; mount the Metrics as the handler for /metrics.
+package prommetrics
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isee-systems/sd-ai/chat"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the generation
+// latency histogram buckets.
+var latencyBuckets = []float64{0.5, 1, 2, 4, 8, 16, 32, 64, 128, 256}
+
+// Metrics implements chat.Metrics, counting events in memory and serving
+// them over HTTP to a Prometheus scraper.
+type Metrics struct {
+	mu           sync.Mutex
+	generations  uint64
+	failures     map[chat.FailureKind]uint64
+	retries      uint64
+	bucketCounts []uint64
+	latencySum   float64
+}
+
+var (
+	_ chat.Metrics = &Metrics{}
+	_ http.Handler = &Metrics{}
+)
+
+func New() *Metrics {
+	return &Metrics{
+		failures:     make(map[chat.FailureKind]uint64),
+		bucketCounts: make([]uint64, len(latencyBuckets)),
+	}
+}
+
+func (m *Metrics) ObserveGeneration(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generations++
+	seconds := latency.Seconds()
+	m.latencySum += seconds
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			m.bucketCounts[i]++
+		}
+	}
+}
+
+func (m *Metrics) IncFailure(kind chat.FailureKind) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures[kind]++
+}
+
+func (m *Metrics) IncRetry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retries++
+}
+
+// String renders the metrics in the Prometheus text format.
+func (m *Metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP sdai_generations_total Number of maps generated, successfully or not.\n")
+	b.WriteString("# TYPE sdai_generations_total counter\n")
+	fmt.Fprintf(&b, "sdai_generations_total %d\n", m.generations)
+
+	b.WriteString("# HELP sdai_generation_failures_total Number of failed generation attempts, by kind of failure.\n")
+	b.WriteString("# TYPE sdai_generation_failures_total counter\n")
+	kinds := make([]string, 0, len(m.failures))
+	for kind := range m.failures {
+		kinds = append(kinds, string(kind))
+	}
+	slices.Sort(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "sdai_generation_failures_total{kind=%q} %d\n", kind, m.failures[chat.FailureKind(kind)])
+	}
+
+	b.WriteString("# HELP sdai_request_retries_total Number of requests re-sent to the provider after a retryable error.\n")
+	b.WriteString("# TYPE sdai_request_retries_total counter\n")
+	fmt.Fprintf(&b, "sdai_request_retries_total %d\n", m.retries)
+
+	b.WriteString("# HELP sdai_generation_duration_seconds How long generating a map took, including repairs.\n")
+	b.WriteString("# TYPE sdai_generation_duration_seconds histogram\n")
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(&b, "sdai_generation_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.bucketCounts[i])
+	}
+	fmt.Fprintf(&b, "sdai_generation_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.generations)
+	fmt.Fprintf(&b, "sdai_generation_duration_seconds_sum %g\n", m.latencySum)
+	fmt.Fprintf(&b, "sdai_generation_duration_seconds_count %d\n", m.generations)
+
+	return b.String()
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(m.String()))
+}
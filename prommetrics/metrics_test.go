@@ -0,0 +1,38 @@
+package prommetrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/isee-systems/sd-ai/chat"
+)
+
+func TestExposition(t *testing.T) {
+	m := New()
+	m.ObserveGeneration(3 * time.Second)
+	m.IncFailure(chat.FailureParse)
+	m.IncFailure(chat.FailureParse)
+	m.IncRetry()
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	out := string(body)
+	assert.Contains(t, out, "sdai_generations_total 1\n")
+	assert.Contains(t, out, `sdai_generation_failures_total{kind="parse"} 2`+"\n")
+	assert.Contains(t, out, "sdai_request_retries_total 1\n")
+	assert.Contains(t, out, `sdai_generation_duration_seconds_bucket{le="2"} 0`+"\n")
+	assert.Contains(t, out, `sdai_generation_duration_seconds_bucket{le="4"} 1`+"\n")
+	assert.Contains(t, out, "sdai_generation_duration_seconds_sum 3\n")
+}
@@ -1,6 +1,21 @@
 package schema
 
-const URL = "http://json-schema.org/draft-07/schema#"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Draft identifies a version of JSON Schema by its meta-schema URL, as
+// used for $schema.
+type Draft string
+
+const (
+	Draft07     Draft = "http://json-schema.org/draft-07/schema#"
+	Draft202012 Draft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+const URL = string(Draft07)
 
 type Type string
 
@@ -8,6 +23,8 @@ const (
 	String Type = "string"
 	Array  Type = "array"
 	Object Type = "object"
+	Number Type = "number"
+	Null   Type = "null"
 )
 
 // JSON is a way to describe a JSON Schema
@@ -17,7 +34,163 @@ type JSON struct {
 	Properties           map[string]*JSON `json:"properties,omitempty"`
 	Items                *JSON            `json:"items,omitempty"`
 	Enum                 []string         `json:"enum,omitempty"`
+	MinLength            *int             `json:"minLength,omitempty"`
+	Minimum              *float64         `json:"minimum,omitempty"`
+	Maximum              *float64         `json:"maximum,omitempty"`
+	MinItems             *int             `json:"minItems,omitempty"`
+	MaxItems             *int             `json:"maxItems,omitempty"`
 	Required             []string         `json:"required,omitempty"`
 	AdditionalProperties *bool            `json:"additionalProperties,omitzero"`
 	Schema               string           `json:"$schema,omitempty"`
+
+	// Nullable allows null as well as Type, written as "type": [Type,
+	// "null"].  Providers with strict schemas require every property, so
+	// this is how an optional value is expressed.
+	Nullable bool `json:"-"`
+}
+
+// jsonFields is JSON without its methods, for encoding and decoding the
+// fields other than type.
+type jsonFields JSON
+
+func (s JSON) MarshalJSON() ([]byte, error) {
+	var t any = s.Type
+	if s.Nullable {
+		t = []Type{s.Type, Null}
+	}
+	return json.Marshal(struct {
+		Type any `json:"type"`
+		*jsonFields
+	}{Type: t, jsonFields: (*jsonFields)(&s)})
+}
+
+func (s *JSON) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		Type json.RawMessage `json:"type"`
+		*jsonFields
+	}{jsonFields: (*jsonFields)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Type) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Type, &s.Type); err == nil {
+		return nil
+	}
+
+	var types []Type
+	if err := json.Unmarshal(aux.Type, &types); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings: %w", err)
+	}
+	s.Type, s.Nullable = "", false
+	for _, t := range types {
+		switch {
+		case t == Null:
+			s.Nullable = true
+		case s.Type == "":
+			s.Type = t
+		default:
+			return fmt.Errorf("unsupported type union %v", types)
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the schema, for tailoring a shared schema
+// without modifying it.
+func (s *JSON) Clone() *JSON {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	if s.Properties != nil {
+		c.Properties = make(map[string]*JSON, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = prop.Clone()
+		}
+	}
+	c.Items = s.Items.Clone()
+	c.Enum = append([]string(nil), s.Enum...)
+	c.Required = append([]string(nil), s.Required...)
+	if s.MinLength != nil {
+		c.MinLength = new(int)
+		*c.MinLength = *s.MinLength
+	}
+	if s.Minimum != nil {
+		c.Minimum = new(float64)
+		*c.Minimum = *s.Minimum
+	}
+	if s.Maximum != nil {
+		c.Maximum = new(float64)
+		*c.Maximum = *s.Maximum
+	}
+	if s.MinItems != nil {
+		c.MinItems = new(int)
+		*c.MinItems = *s.MinItems
+	}
+	if s.MaxItems != nil {
+		c.MaxItems = new(int)
+		*c.MaxItems = *s.MaxItems
+	}
+	if s.AdditionalProperties != nil {
+		c.AdditionalProperties = new(bool)
+		*c.AdditionalProperties = *s.AdditionalProperties
+	}
+	return &c
+}
+
+// Validate checks that the schema's draft (named by the root's $schema,
+// or draft-07 if unset) is one this package supports, and that the
+// schema is valid under it.  The keywords this package supports mean the
+// same in draft-07 and draft 2020-12, except that only the root may
+// declare $schema in 2020-12, which is enforced for both.
+func (s *JSON) Validate() error {
+	switch Draft(s.Schema) {
+	case "", Draft07, Draft202012:
+	default:
+		return fmt.Errorf("unsupported $schema %q", s.Schema)
+	}
+	return s.validate("#", true)
+}
+
+func (s *JSON) validate(path string, root bool) error {
+	if !root && s.Schema != "" {
+		return fmt.Errorf("%s: $schema is only allowed on the root schema", path)
+	}
+	if !slices.Contains([]Type{String, Number, Array, Object}, s.Type) {
+		return fmt.Errorf("%s: unsupported type %q", path, s.Type)
+	}
+	if len(s.Enum) > 0 && s.Type != String {
+		return fmt.Errorf("%s: enum is only supported on strings", path)
+	}
+	if (s.Minimum != nil || s.Maximum != nil) && s.Type != Number {
+		return fmt.Errorf("%s: minimum and maximum are only supported on numbers", path)
+	}
+	if s.Type == Array && s.Items == nil {
+		return fmt.Errorf("%s: array without items", path)
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return fmt.Errorf("%s: required property %q is not defined", path, name)
+		}
+	}
+
+	if s.Items != nil {
+		if err := s.Items.validate(path+"/items", false); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := s.Properties[name].validate(path+"/properties/"+name, false); err != nil {
+			return err
+		}
+	}
+	return nil
 }
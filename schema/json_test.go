@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	s := &JSON{
+		Type:   Object,
+		Schema: string(Draft202012),
+		Properties: map[string]*JSON{
+			"names": {Type: Array, Items: &JSON{Type: String, Enum: []string{"a", "b"}}},
+		},
+		Required: []string{"names"},
+	}
+	assert.NoError(t, s.Validate())
+
+	s.Schema = "draft-03"
+	assert.ErrorContains(t, s.Validate(), `unsupported $schema "draft-03"`)
+	s.Schema = string(Draft07)
+
+	s.Properties["names"].Items.Schema = string(Draft07)
+	assert.ErrorContains(t, s.Validate(), "#/properties/names/items: $schema is only allowed on the root schema")
+	s.Properties["names"].Items.Schema = ""
+
+	one := 1.0
+	s.Properties["weight"] = &JSON{Type: Number, Maximum: &one}
+	assert.NoError(t, s.Validate())
+	s.Properties["weight"].Type = String
+	assert.ErrorContains(t, s.Validate(), "#/properties/weight: minimum and maximum are only supported on numbers")
+	delete(s.Properties, "weight")
+
+	s.Required = append(s.Required, "ages")
+	assert.ErrorContains(t, s.Validate(), `#: required property "ages" is not defined`)
+}
+
+func TestNullable(t *testing.T) {
+	one := 1.0
+	s := &JSON{
+		Type:       Object,
+		Properties: map[string]*JSON{"weight": {Type: Number, Maximum: &one, Nullable: true}},
+		Required:   []string{"weight"},
+	}
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "object", "properties": {"weight": {"type": ["number", "null"], "maximum": 1}}, "required": ["weight"]}`, string(b))
+
+	var decoded JSON
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, s, &decoded)
+	assert.NoError(t, decoded.Validate())
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"type": ["number", "string"]}`), &decoded), "unsupported type union")
+}
@@ -235,10 +235,10 @@ func TestMultipleFeedbackLoops(t *testing.T) {
 					relationships = append(relationships, additionalRelationships...)
 				}
 
-				c, err := openai.NewClient(openai.OllamaURL, llm)
+				c, err := openai.NewClient(openai.OllamaURL, llm.Name)
 				require.NoError(t, err)
 
-				d := causal.NewDiagrammer(c)
+				d := causal.NewDiagrammer(c, causal.WithProfile(llm))
 
 				debugDir := path.Join(".", "testdata", "translation", "multiple_loop", name)
 				err = os.RemoveAll(debugDir)